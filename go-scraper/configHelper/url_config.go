@@ -12,6 +12,87 @@ type StateURLs struct {
 	BaseURL   string   `json:"baseUrl"`
 	HomePageUrl   string   `json:"homePageUrl"`
 	URLs      []string `json:"urls"`
+	Concurrency int    `json:"concurrency,omitempty"` // Optional per-state override of the -concurrency flag
+	Politeness *PolitenessConfig `json:"politeness,omitempty"`
+	Extractor  *ExtractorConfig  `json:"extractor,omitempty"` // Optional selectors, replaces the state's Go extractor
+	Gatherer   *GathererConfig   `json:"gatherer,omitempty"`
+}
+
+// PolitenessConfig is the per-state throttling applied to every host of that state
+type PolitenessConfig struct {
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	Burst             int     `json:"burst"`
+	MinDelayMS        int     `json:"minDelayMs"`
+	JitterMS          int     `json:"jitterMs"`
+}
+
+// GathererConfig picks how the park URLs of a state are found, e.g.
+//
+//	"gatherer": { "type": "html-links", "sectionSelector": "section#564717", "hrefPattern": "/dnr/state-parks/parks-lakes/" }
+//
+// For sitemap, homePageUrl is the sitemap or sitemap index. Which fields are used depends on Type. static-list uses the state's "urls" array, which is
+// also the default for states that list urls but have no gatherer.
+type GathererConfig struct {
+	Type            string `json:"type"`                      // json-list, html-links, sitemap or static-list
+	JSONPath        string `json:"jsonPath,omitempty"`        // json-list: path to the links, e.g. "listItems[].meta.dynamicPageLink"
+	SectionSelector string `json:"sectionSelector,omitempty"` // html-links: only links inside matching elements are used
+	HrefPattern     string `json:"hrefPattern,omitempty"`     // html-links, sitemap: regex park URLs must match
+	MergeStatic     bool   `json:"mergeStatic,omitempty"`     // Also scrape the state's static urls, on top of the gathered ones
+	Pagination      *PaginationConfig `json:"pagination,omitempty"` // json-list, html-links: how to find the next listing page
+}
+
+// PaginationConfig describes how a listing spreads over several pages, e.g.
+//
+//	"pagination": { "type": "offset", "param": "offset", "limitParam": "limit", "pageSize": 20 }
+//
+// Types are rel-next (Link header or rel="next" link), next-button, offset and cursor.
+type PaginationConfig struct {
+	Type       string `json:"type"`
+	Selector   string `json:"selector,omitempty"`   // next-button: CSS selector of the link to the next page
+	Param      string `json:"param,omitempty"`      // offset, cursor: query parameter carrying the offset or cursor
+	LimitParam string `json:"limitParam,omitempty"` // offset: optional query parameter set to pageSize
+	PageSize   int    `json:"pageSize,omitempty"`   // offset: how far the offset moves per page
+	CursorPath string `json:"cursorPath,omitempty"` // cursor: path of the next cursor in the JSON, e.g. "meta.nextCursor"
+	MaxPages   int    `json:"maxPages,omitempty"`   // Safety cap on pages walked, 50 when unset
+}
+
+// ExtractorConfig declares how to read a park from a park page with CSS selectors, e.g.
+//
+//	"extractor": {
+//	  "name":       { "selector": "h1" },
+//	  "latitude":   { "selector": "div.park-lat p" },
+//	  "longitude":  { "selector": "div.park-lon p" },
+//	  "address":    { "selector": "div#property-add p", "regex": "Address:\\s*(.+)" },
+//	  "activities": { "selector": "div#Activities li" }
+//	}
+//
+// Only name is required. Without usable coordinates the address is geocoded. With type
+// "schema-org" no selectors are needed; the page's schema.org JSON-LD or microdata is read instead.
+// "builtin" is the Go extractor written for the state. "geocode" only supplies coordinates, by
+// geocoding the address the layers above it found (or the one its address selector picks).
+// "composite" merges its layers, e.g.
+//
+//	"extractor": { "type": "composite", "layers": [ { "type": "builtin" }, { "type": "schema-org" }, { "type": "geocode" } ] }
+//
+// where for each field the first layer that finds it wins, even if that layer failed to find
+// every field it needs on its own. With a geocode layer the other layers don't geocode.
+type ExtractorConfig struct {
+	Type       string            `json:"type,omitempty"`   // css (default), schema-org, builtin, geocode or composite
+	Layers     []ExtractorConfig `json:"layers,omitempty"` // composite: extractors in priority order
+	Name       SelectorConfig `json:"name"`
+	Latitude   SelectorConfig `json:"latitude"`
+	Longitude  SelectorConfig `json:"longitude"`
+	Address    SelectorConfig `json:"address"`
+	Activities SelectorConfig `json:"activities"` // Every match is one activity
+}
+
+// SelectorConfig picks a value from the page. The text of the first element matching Selector
+// is used, or its Attr attribute when set. Regex optionally post-processes the value: its first
+// capture group is kept, or the whole match when it has no groups.
+type SelectorConfig struct {
+	Selector string `json:"selector"`
+	Attr     string `json:"attr,omitempty"`
+	Regex    string `json:"regex,omitempty"`
 }
 
 // URLConfig holds the configuration of URLs by state
@@ -19,6 +100,10 @@ type URLConfig struct {
 	stateURLMap        map[string][]string
 	stateBaseURLMap    map[string]string
 	stateHomePageURLMap map[string]string
+	stateConcurrencyMap map[string]int
+	statePolitenessMap  map[string]PolitenessConfig
+	stateExtractorMap   map[string]ExtractorConfig
+	stateGathererMap    map[string]GathererConfig
 }
 
 // LoadURLConfig reads urls.json and returns a URLConfig
@@ -39,16 +124,36 @@ func LoadURLConfig(filepath string) (*URLConfig, error) {
 	stateURLMap := make(map[string][]string)
 	stateBaseURLMap := make(map[string]string)
 	stateHomePageURLMap := make(map[string]string)
+	stateConcurrencyMap := make(map[string]int)
+	statePolitenessMap := make(map[string]PolitenessConfig)
+	stateExtractorMap := make(map[string]ExtractorConfig)
+	stateGathererMap := make(map[string]GathererConfig)
 	for _, state := range stateURLs {
 		stateURLMap[state.StateCode] = state.URLs
 		stateBaseURLMap[state.StateCode] = state.BaseURL
 		stateHomePageURLMap[state.StateCode] = state.HomePageUrl
+		if state.Concurrency > 0 {
+			stateConcurrencyMap[state.StateCode] = state.Concurrency
+		}
+		if state.Politeness != nil {
+			statePolitenessMap[state.StateCode] = *state.Politeness
+		}
+		if state.Extractor != nil {
+			stateExtractorMap[state.StateCode] = *state.Extractor
+		}
+		if state.Gatherer != nil {
+			stateGathererMap[state.StateCode] = *state.Gatherer
+		}
 	}
 
 	return &URLConfig{
 		stateURLMap:        stateURLMap,
 		stateBaseURLMap:    stateBaseURLMap,
 		stateHomePageURLMap: stateHomePageURLMap,
+		stateConcurrencyMap: stateConcurrencyMap,
+		statePolitenessMap:  statePolitenessMap,
+		stateExtractorMap:   stateExtractorMap,
+		stateGathererMap:    stateGathererMap,
 	}, nil
 }
 
@@ -83,3 +188,31 @@ func (c *URLConfig) GetHomePageURLByState(stateCode string) (string, bool) {
 	homePageURL, ok := c.stateHomePageURLMap[stateCode]
 	return homePageURL, ok
 }
+
+// GetConcurrencyByState returns the configured scrape concurrency for a given state code.
+// ok is false when the state does not override the global setting.
+func (c *URLConfig) GetConcurrencyByState(stateCode string) (int, bool) {
+	concurrency, ok := c.stateConcurrencyMap[stateCode]
+	return concurrency, ok
+}
+
+// GetPolitenessByState returns the throttling settings for a given state code.
+// ok is false when the state relies on the default policy.
+func (c *URLConfig) GetPolitenessByState(stateCode string) (PolitenessConfig, bool) {
+	politeness, ok := c.statePolitenessMap[stateCode]
+	return politeness, ok
+}
+
+// GetExtractorByState returns the declarative extractor settings for a given state code.
+// ok is false when the state uses a Go extractor.
+func (c *URLConfig) GetExtractorByState(stateCode string) (ExtractorConfig, bool) {
+	extractor, ok := c.stateExtractorMap[stateCode]
+	return extractor, ok
+}
+
+// GetGathererByState returns how park URLs are gathered for a given state code.
+// ok is false when the state has no gatherer configured.
+func (c *URLConfig) GetGathererByState(stateCode string) (GathererConfig, bool) {
+	gatherer, ok := c.stateGathererMap[stateCode]
+	return gatherer, ok
+}
@@ -0,0 +1,189 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrNotDeadLettered is part of the error Close returns when an event that failed for good
+// could not be written to the dead-letter queue either, so it is lost
+var ErrNotDeadLettered = errors.New("event could not be dead-lettered")
+
+// DeadLetter is an event a subscriber still failed to handle after every retry
+type DeadLetter struct {
+	Subscriber string
+	Event      Event
+	Err        string // Error of the last attempt
+	Attempts   int
+	FailedAt   time.Time
+}
+
+// deadLetterRecord is a DeadLetter as written to the dead-letter file, one JSON object per line
+type deadLetterRecord struct {
+	Subscriber string          `json:"subscriber"`
+	Error      string          `json:"error"`
+	Attempts   int             `json:"attempts"`
+	FailedAt   time.Time       `json:"failedAt"`
+	Event      json.RawMessage `json:"event"`
+}
+
+// DeadLetterQueue keeps dead letters in one append-only file per subscriber, named after it,
+// so they can be replayed to that subscriber later
+type DeadLetterQueue struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewDeadLetterQueue creates a dead-letter queue in dir. The directory is created on the first dead letter.
+func NewDeadLetterQueue(dir string) *DeadLetterQueue {
+	return &DeadLetterQueue{dir: dir}
+}
+
+// Path returns the dead-letter file of subscriber
+func (d *DeadLetterQueue) Path(subscriber string) string {
+	return filepath.Join(d.dir, safeFileName(subscriber)+".jsonl")
+}
+
+// Add appends letter to its subscriber's dead-letter file
+func (d *DeadLetterQueue) Add(letter DeadLetter) error {
+	data, err := encodeEvent(letter.Event)
+	if err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(deadLetterRecord{
+		Subscriber: letter.Subscriber,
+		Error:      letter.Err,
+		Attempts:   letter.Attempts,
+		FailedAt:   letter.FailedAt,
+		Event:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+
+	file, err := os.OpenFile(d.Path(letter.Subscriber), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	return file.Close()
+}
+
+// Take moves the dead letters of subscriber out of the queue and returns them, oldest first,
+// together with a function that discards them for good. Until that is called they are kept
+// aside and come back with the next Take, so a replay that dies halfway loses nothing.
+func (d *DeadLetterQueue) Take(subscriber string) ([]DeadLetter, func() error, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path := d.Path(subscriber)
+	takenPath := path + ".taken"
+
+	// Letters taken by an earlier replay that never finished go first
+	if _, err := os.Stat(takenPath); errors.Is(err, fs.ErrNotExist) {
+		if err := os.Rename(path, takenPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, fmt.Errorf("failed to take dead letters: %w", err)
+		}
+	} else if err == nil {
+		if err := appendFile(takenPath, path); err != nil {
+			return nil, nil, fmt.Errorf("failed to take dead letters: %w", err)
+		}
+	} else {
+		return nil, nil, fmt.Errorf("failed to take dead letters: %w", err)
+	}
+
+	letters, err := readDeadLetters(takenPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	discard := func() error {
+		if err := os.Remove(takenPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove taken dead letters: %w", err)
+		}
+		return nil
+	}
+	return letters, discard, nil
+}
+
+// appendFile moves the contents of src to the end of dst and removes src, if it exists
+func appendFile(dst string, src string) error {
+	data, err := os.ReadFile(src)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(dst, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// readDeadLetters reads a dead-letter file. A missing file has no letters.
+func readDeadLetters(path string) ([]DeadLetter, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	defer file.Close()
+
+	letters := make([]DeadLetter, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var record deadLetterRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+
+		event, err := decodeEvent(record.Event)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+
+		letters = append(letters, DeadLetter{
+			Subscriber: record.Subscriber,
+			Event:      event,
+			Err:        record.Error,
+			Attempts:   record.Attempts,
+			FailedAt:   record.FailedAt,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter file: %w", err)
+	}
+
+	return letters, nil
+}
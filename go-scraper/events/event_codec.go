@@ -0,0 +1,92 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// encodedEvent is how an Event is written to disk. Error fields don't survive JSON, so the
+// event's error, if it has one, is carried as text next to it.
+type encodedEvent struct {
+	Type  EventType       `json:"type"`
+	Error string          `json:"error,omitempty"`
+	Event json.RawMessage `json:"event"`
+}
+
+// encodeEvent marshals event together with its type so decodeEvent can restore it
+func encodeEvent(event Event) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s event: %w", event.Type(), err)
+	}
+
+	encoded := encodedEvent{Type: event.Type(), Event: data}
+	if err := eventError(event); err != nil {
+		encoded.Error = err.Error()
+	}
+	return json.Marshal(encoded)
+}
+
+// decodeEvent restores an event written by encodeEvent. Its error, if any, comes back as plain text.
+func decodeEvent(data []byte) (Event, error) {
+	var encoded encodedEvent
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+
+	var eventErr error
+	if encoded.Error != "" {
+		eventErr = errors.New(encoded.Error)
+	}
+
+	switch encoded.Type {
+	case RunStartedType:
+		return decodeAs[RunStarted](encoded.Event)
+	case StateStartedType:
+		return decodeAs[StateStarted](encoded.Event)
+	case UrlsGatheredType:
+		return decodeAs[UrlsGathered](encoded.Event)
+	case ParkScrapedType:
+		return decodeAs[ParkScrapedEvent](encoded.Event)
+	case ParkScrapeFailedType:
+		event, err := decodeAs[ParkScrapeFailed](encoded.Event)
+		event.Err = eventErr
+		return event, err
+	case GeocodeFailedType:
+		event, err := decodeAs[GeocodeFailed](encoded.Event)
+		event.Err = eventErr
+		return event, err
+	case StateCompletedType:
+		event, err := decodeAs[StateCompleted](encoded.Event)
+		event.Err = eventErr
+		return event, err
+	case RunCompletedType:
+		return decodeAs[RunCompleted](encoded.Event)
+	default:
+		return nil, fmt.Errorf("unknown event type %q", encoded.Type)
+	}
+}
+
+// decodeAs unmarshals data into an event of type T
+func decodeAs[T Event](data json.RawMessage) (T, error) {
+	var event T
+	if err := json.Unmarshal(data, &event); err != nil {
+		return event, fmt.Errorf("failed to unmarshal %s event: %w", event.Type(), err)
+	}
+	return event, nil
+}
+
+// eventError returns the error carried by event, if its type has one
+func eventError(event Event) error {
+	switch e := event.(type) {
+	case ParkScrapeFailed:
+		return e.Err
+	case GeocodeFailed:
+		return e.Err
+	case StateCompleted:
+		return e.Err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"errors"
+	"reflect"
+	"scraper/models"
+	"testing"
+	"time"
+)
+
+func TestEventCodecRoundTrips(t *testing.T) {
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	url := "https://dnr.illinois.gov/parks/park.starved-rock.html"
+	failure := errors.New("timeout after 30s")
+
+	tests := []Event{
+		RunStarted{RunID: "20250601-120000", States: []string{"IL", "IN"}, Resumed: true, Timestamp: at},
+		StateStarted{StateCode: "IL", Timestamp: at},
+		UrlsGathered{StateCode: "IL", URLs: []string{url}, Timestamp: at},
+		ParkScrapedEvent{
+			Park:      &models.Park{Name: "Starved Rock State Park", StateCode: "IL", Latitude: 41.3, Longitude: -88.9},
+			StateCode: "IL",
+			URL:       url,
+			Duration:  1500 * time.Millisecond,
+			Timestamp: at,
+			Warnings:  []string{"no phone number"},
+		},
+		ParkScrapeFailed{StateCode: "IL", URL: url, Attempts: 5, Err: failure, Timestamp: at},
+		GeocodeFailed{StateCode: "IL", URL: url, Address: "2678 E 875th Rd, Oglesby, IL", Err: failure, Timestamp: at},
+		StateCompleted{StateCode: "IL", Succeeded: 40, Failed: 2, Skipped: 1, Unfinished: 3, Duration: time.Minute, Err: failure, Timestamp: at},
+		StateCompleted{StateCode: "IN", Succeeded: 30, Duration: time.Minute, Timestamp: at},
+		RunCompleted{RunID: "20250601-120000", Interrupted: true, Succeeded: 70, Failed: 2, Duration: 2 * time.Minute, Timestamp: at},
+	}
+
+	for _, event := range tests {
+		t.Run(string(event.Type()), func(t *testing.T) {
+			data, err := encodeEvent(event)
+			if err != nil {
+				t.Fatalf("encodeEvent: %v", err)
+			}
+			decoded, err := decodeEvent(data)
+			if err != nil {
+				t.Fatalf("decodeEvent: %v", err)
+			}
+
+			// The error comes back as text, so it is compared as text
+			want, got := eventError(event), eventError(decoded)
+			if (want == nil) != (got == nil) || (want != nil && want.Error() != got.Error()) {
+				t.Errorf("got error %v, want %v", got, want)
+			}
+			if !reflect.DeepEqual(withoutError(decoded), withoutError(event)) {
+				t.Errorf("got %+v, want %+v", decoded, event)
+			}
+		})
+	}
+}
+
+func TestDecodeEventRejectsUnknownType(t *testing.T) {
+	if _, err := decodeEvent([]byte(`{"type":"ParkRenamed","event":{}}`)); err == nil {
+		t.Error("decodeEvent of an unknown type succeeded, want an error")
+	}
+}
+
+// withoutError returns event with its error, if its type has one, cleared
+func withoutError(event Event) Event {
+	switch e := event.(type) {
+	case ParkScrapeFailed:
+		e.Err = nil
+		return e
+	case GeocodeFailed:
+		e.Err = nil
+		return e
+	case StateCompleted:
+		e.Err = nil
+		return e
+	default:
+		return event
+	}
+}
@@ -0,0 +1,92 @@
+package events
+
+import "time"
+
+// EventType identifies the kind of an Event, so subscribers can choose what they receive
+type EventType string
+
+const (
+	RunStartedType       EventType = "RunStarted"
+	StateStartedType     EventType = "StateStarted"
+	UrlsGatheredType     EventType = "UrlsGathered"
+	ParkScrapedType      EventType = "ParkScraped"
+	ParkScrapeFailedType EventType = "ParkScrapeFailed"
+	GeocodeFailedType    EventType = "GeocodeFailed"
+	StateCompletedType   EventType = "StateCompleted"
+	RunCompletedType     EventType = "RunCompleted"
+)
+
+// Event is implemented by everything the publisher delivers
+type Event interface {
+	Type() EventType
+}
+
+// RunStarted is published once, before any state is scraped
+type RunStarted struct {
+	RunID     string
+	States    []string // States the run will try to scrape
+	Resumed   bool     // Whether the run continues an interrupted one
+	Timestamp time.Time
+}
+
+// StateStarted is published when scraping of a state begins
+type StateStarted struct {
+	StateCode string
+	Timestamp time.Time
+}
+
+// UrlsGathered is published once the park URLs of a state are known
+type UrlsGathered struct {
+	StateCode string
+	URLs      []string
+	Timestamp time.Time
+}
+
+// ParkScrapeFailed is published for a park page that could not be scraped
+type ParkScrapeFailed struct {
+	StateCode string
+	URL       string
+	Attempts  int
+	Err       error `json:"-"`
+	Timestamp time.Time
+}
+
+// GeocodeFailed is published when an address found on a park page could not be geocoded
+type GeocodeFailed struct {
+	StateCode string
+	URL       string // Park page the address came from, if known
+	Address   string
+	Err       error `json:"-"`
+	Timestamp time.Time
+}
+
+// StateCompleted is published when a state is done, including when it failed or was interrupted
+type StateCompleted struct {
+	StateCode  string
+	Succeeded  int
+	Failed     int
+	Skipped    int
+	Unfinished int
+	Duration   time.Duration
+	Err        error `json:"-"` // Why the state failed or was cut short, nil if it completed
+	Timestamp  time.Time
+}
+
+// RunCompleted is published once after the last state
+type RunCompleted struct {
+	RunID       string
+	Interrupted bool
+	Succeeded   int
+	Failed      int
+	Duration    time.Duration
+	Timestamp   time.Time
+}
+
+func (RunStarted) Type() EventType       { return RunStartedType }
+func (StateStarted) Type() EventType     { return StateStartedType }
+func (UrlsGathered) Type() EventType     { return UrlsGatheredType }
+func (ParkScrapedEvent) Type() EventType { return ParkScrapedType }
+func (ParkScrapeFailed) Type() EventType { return ParkScrapeFailedType }
+func (GeocodeFailed) Type() EventType    { return GeocodeFailedType }
+func (StateCompleted) Type() EventType   { return StateCompletedType }
+func (RunCompleted) Type() EventType     { return RunCompletedType }
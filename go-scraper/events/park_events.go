@@ -1,7 +1,13 @@
 package events
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
 	"scraper/models"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,74 +18,283 @@ type ParkScrapedEvent struct {
 	URL       string
 	Duration  time.Duration
 	Timestamp time.Time
+	Warnings  []string // Non-fatal problems the extractor had with the page
 }
 
 // ParkEventSubscriber is the interface for park event subscribers
 type ParkEventSubscriber interface {
-	OnParkScraped(event ParkScrapedEvent)
+	// OnParkScraped handles a single event. ctx is cancelled when the publisher gives up
+	// on delivery, e.g. when a shutdown deadline passes. A returned error makes the publisher
+	// retry the event as the subscriber's retry policy allows, then dead-letter it.
+	OnParkScraped(ctx context.Context, event ParkScrapedEvent) error
 }
 
-// ParkEventPublisher manages subscribers and publishes events
+// DeferredParkEventSubscriber is implemented by subscribers that accept an event in OnParkScraped
+// but only finish it later, such as writers that send events in batches. The publisher then only
+// counts the event as handled, for Flush and for final subscribers, once the subscriber calls done.
+type DeferredParkEventSubscriber interface {
+	ParkEventSubscriber
+	// OnParkScrapedDeferred accepts event like OnParkScraped, and calls done once it is finished
+	// with the event, possibly from another goroutine: with true if it handled the event, with
+	// false if it gave up on it. If it returns an error the event was not accepted, and done must
+	// not be called.
+	OnParkScrapedDeferred(ctx context.Context, event ParkScrapedEvent, done func(delivered bool)) error
+}
+
+// EventSubscriber is the interface for subscribers to the lifecycle events of a run
+type EventSubscriber interface {
+	// OnEvent handles a single event of one of the types the subscriber subscribed to.
+	// Use a type switch to get at the event's fields. ctx and errors are as for OnParkScraped.
+	OnEvent(ctx context.Context, event Event) error
+}
+
+// subscription is a subscriber's queue together with the event types it wants
+type subscription struct {
+	types map[EventType]bool // nil means every type
+	queue *subscriberQueue
+}
+
+// wants reports whether the subscription receives events of type t
+func (s subscription) wants(t EventType) bool {
+	return s.types == nil || s.types[t]
+}
+
+// ParkEventPublisher manages subscribers and publishes events. Every subscriber gets its own
+// queue and goroutine, so a slow subscriber only holds up itself.
 type ParkEventPublisher struct {
-	subscribers []ParkEventSubscriber
-	eventQueue  chan ParkScrapedEvent
-	done        chan bool
+	ctx         context.Context
+	mu          sync.RWMutex // Held for reading while publishing, so Close can wait for Publish calls in progress
+	closed      bool
+	subscribers []subscription
+	finals      []subscription // Get each event after every subscriber in subscribers is done with it
+	inflight    inflight
+	closeOnce   sync.Once
+	closeErr    error
+}
+
+// NewParkEventPublisher creates a new event publisher. ctx is handed to subscribers with every
+// event; cancel it to abandon deliveries that are still in progress.
+func NewParkEventPublisher(ctx context.Context) *ParkEventPublisher {
+	return &ParkEventPublisher{
+		ctx:         ctx,
+		subscribers: make([]subscription, 0),
+	}
 }
 
-// NewParkEventPublisher creates a new event publisher
-func NewParkEventPublisher() *ParkEventPublisher {
-	p := &ParkEventPublisher{
-		subscribers: make([]ParkEventSubscriber, 0),
-		eventQueue:  make(chan ParkScrapedEvent, 100), // Buffer 100 events
-		done:        make(chan bool),
+// Subscribe adds a subscriber to receive ParkScraped events through a queue that blocks when full
+func (p *ParkEventPublisher) Subscribe(subscriber ParkEventSubscriber) {
+	if err := p.SubscribeWithOptions(subscriber, QueueOptions{}); err != nil {
+		// Only spilling to disk can fail, and the default queue doesn't
+		panic(err)
 	}
+}
 
-	// Start event processing goroutine
-	go p.processEvents()
+// SubscribeWithOptions adds a subscriber to receive ParkScraped events through a queue configured by options
+func (p *ParkEventPublisher) SubscribeWithOptions(subscriber ParkEventSubscriber, options QueueOptions) error {
+	return p.subscribe(&p.subscribers, subscriber, options, parkScrapedDeliverer(subscriber), ParkScrapedType)
+}
 
-	return p
+// SubscribeEvents adds a subscriber to receive events of the given types, or of every type if none are given
+func (p *ParkEventPublisher) SubscribeEvents(subscriber EventSubscriber, types ...EventType) {
+	if err := p.SubscribeEventsWithOptions(subscriber, QueueOptions{}, types...); err != nil {
+		// As in Subscribe, the default queue can't fail
+		panic(err)
+	}
 }
 
-// Subscribe adds a subscriber to receive events
-func (p *ParkEventPublisher) Subscribe(subscriber ParkEventSubscriber) {
-	p.subscribers = append(p.subscribers, subscriber)
-}
-
-// Publish sends an event to all subscribers via the queue
-func (p *ParkEventPublisher) Publish(event ParkScrapedEvent) {
-	p.eventQueue <- event
-}
-
-// processEvents processes events from the queue in the background
-func (p *ParkEventPublisher) processEvents() {
-	for {
-		select {
-		case event := <-p.eventQueue:
-			// Notify all subscribers
-			for _, subscriber := range p.subscribers {
-				subscriber.OnParkScraped(event)
-			}
-		case <-p.done:
-			// Drain remaining events before exiting
-			for len(p.eventQueue) > 0 {
-				event := <-p.eventQueue
-				for _, subscriber := range p.subscribers {
-					subscriber.OnParkScraped(event)
-				}
-			}
+// SubscribeEventsWithOptions is SubscribeEvents with a queue configured by options
+func (p *ParkEventPublisher) SubscribeEventsWithOptions(subscriber EventSubscriber, options QueueOptions, types ...EventType) error {
+	deliver := func(ctx context.Context, event Event, done func(delivered bool)) (bool, error) {
+		return false, subscriber.OnEvent(ctx, event)
+	}
+	return p.subscribe(&p.subscribers, subscriber, options, deliver, types...)
+}
+
+// SubscribeFinal adds a subscriber that receives each ParkScraped event only after every other
+// subscriber that receives it has handled it, e.g. to record that a park was fully delivered.
+// Events another subscriber failed on, even if they were dead-lettered, or that were dropped or
+// lost never reach the final subscribers.
+func (p *ParkEventPublisher) SubscribeFinal(subscriber ParkEventSubscriber, options QueueOptions) error {
+	return p.subscribe(&p.finals, subscriber, options, parkScrapedDeliverer(subscriber), ParkScrapedType)
+}
+
+// subscribe starts a queue for subscriber and adds it to list
+func (p *ParkEventPublisher) subscribe(list *[]subscription, subscriber any, options QueueOptions, deliver deliverFunc, types ...EventType) error {
+	if options.Name == "" {
+		options.Name = fmt.Sprintf("%T", subscriber)
+	}
+
+	queue, err := newSubscriberQueue(p.ctx, options, deliver)
+	if err != nil {
+		return err
+	}
+
+	var wanted map[EventType]bool
+	if len(types) > 0 {
+		wanted = make(map[EventType]bool, len(types))
+		for _, t := range types {
+			wanted[t] = true
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	*list = append(*list, subscription{types: wanted, queue: queue})
+	return nil
+}
+
+// parkScrapedDeliverer adapts a ParkEventSubscriber to the queue's delivery function
+func parkScrapedDeliverer(subscriber ParkEventSubscriber) deliverFunc {
+	if deferring, ok := subscriber.(DeferredParkEventSubscriber); ok {
+		return func(ctx context.Context, event Event, done func(delivered bool)) (bool, error) {
+			err := deferring.OnParkScrapedDeferred(ctx, event.(ParkScrapedEvent), done)
+			return err == nil, err
+		}
+	}
+	return func(ctx context.Context, event Event, done func(delivered bool)) (bool, error) {
+		return false, subscriber.OnParkScraped(ctx, event.(ParkScrapedEvent))
+	}
+}
+
+// Publish queues an event for every subscriber that wants its type. Depending on a subscriber's
+// overflow policy it may block while that subscriber's queue is full.
+// Events published after Close are dropped.
+func (p *ParkEventPublisher) Publish(event Event) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		log.Printf("[EVENTS] Publisher is closed, dropping %s event", event.Type())
+		return
+	}
+
+	targets := wanting(p.subscribers, event.Type())
+	finals := wanting(p.finals, event.Type())
+	if len(targets) == 0 && len(finals) == 0 {
+		return
+	}
+
+	// The event is in flight until the last final subscriber is done with it. The last of the
+	// other subscribers to finish passes it on to the final ones, if every one of them handled it.
+	p.inflight.add()
+	toFinals := func(delivered bool) {
+		if !delivered || len(finals) == 0 {
+			p.inflight.done()
 			return
 		}
+		done := countdown(len(finals), func(bool) { p.inflight.done() })
+		for _, final := range finals {
+			final.queue.enqueue(queuedEvent{event: event, done: done})
+		}
+	}
+	if len(targets) == 0 {
+		toFinals(true)
+		return
+	}
+
+	done := countdown(len(targets), toFinals)
+	for _, target := range targets {
+		target.queue.enqueue(queuedEvent{event: event, done: done})
+	}
+}
+
+// countdown returns a function that calls f on its n-th call, with whether every call reported
+// the event delivered
+func countdown(n int, f func(delivered bool)) func(delivered bool) {
+	var remaining atomic.Int32
+	var undelivered atomic.Bool
+	remaining.Store(int32(n))
+	return func(delivered bool) {
+		if !delivered {
+			undelivered.Store(true)
+		}
+		if remaining.Add(-1) == 0 {
+			f(!undelivered.Load())
+		}
 	}
 }
 
-// Close stops the event publisher and drains the queue
-func (p *ParkEventPublisher) Close() {
-	p.done <- true
+// wanting returns the subscriptions that receive events of type t
+func wanting(subscriptions []subscription, t EventType) []subscription {
+	wanted := make([]subscription, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		if subscription.wants(t) {
+			wanted = append(wanted, subscription)
+		}
+	}
+	return wanted
 }
 
-// WaitForQueue blocks until all events in the queue are processed
-func (p *ParkEventPublisher) WaitForQueue() {
-	for len(p.eventQueue) > 0 {
-		time.Sleep(10 * time.Millisecond)
+// Flush blocks until every event published so far has been handled by every subscriber that
+// wants it, or until ctx is done
+func (p *ParkEventPublisher) Flush(ctx context.Context) error {
+	select {
+	case <-p.inflight.idle():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the event publisher, blocks until every subscriber has been handed its queued events
+// and returns what went wrong in the subscribers joined into one error: events that failed for
+// good, whether or not they could be dead-lettered, and lost spilled events.
+// It is safe to call more than once; later calls return the same error.
+func (p *ParkEventPublisher) Close() error {
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+
+		// Final subscribers keep receiving events until the others are drained
+		var errs []error
+		for _, subscription := range p.subscribers {
+			errs = append(errs, subscription.queue.close())
+		}
+		for _, subscription := range p.finals {
+			errs = append(errs, subscription.queue.close())
+		}
+		p.closeErr = errors.Join(errs...)
+	})
+	return p.closeErr
+}
+
+// inflight counts the events that are not yet handled by all their subscribers
+type inflight struct {
+	mu     sync.Mutex
+	count  int
+	idleCh chan struct{} // Closed when count drops back to 0
+}
+
+func (f *inflight) add() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.count == 0 {
+		f.idleCh = make(chan struct{})
+	}
+	f.count++
+}
+
+func (f *inflight) done() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.count--
+	if f.count == 0 {
+		close(f.idleCh)
+	}
+}
+
+// idle returns a channel that is closed once no events are in flight
+func (f *inflight) idle() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.count == 0 {
+		closed := make(chan struct{})
+		close(closed)
+		return closed
 	}
+	return f.idleCh
 }
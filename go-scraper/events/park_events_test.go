@@ -0,0 +1,278 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFlushWaitsForEventBeingHandled(t *testing.T) {
+	publisher := NewParkEventPublisher(context.Background())
+	subscriber := newGatedSubscriber()
+	publisher.Subscribe(subscriber)
+	defer publisher.Close()
+
+	publisher.Publish(parkEvent(0))
+	subscriber.waitStarted(t)
+
+	// The event left the queue, but the subscriber is still handling it
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := publisher.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Flush returned %v while the subscriber was busy, want it to wait", err)
+	}
+
+	flushed := make(chan error, 1)
+	go func() { flushed <- publisher.Flush(context.Background()) }()
+	select {
+	case err := <-flushed:
+		t.Fatalf("Flush returned %v before the subscriber was done", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(subscriber.gate)
+	select {
+	case err := <-flushed:
+		if err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Flush didn't return once the subscriber was done")
+	}
+	if want := parkURLs(0); !reflect.DeepEqual(subscriber.handled(), want) {
+		t.Errorf("handled %v, want %v", subscriber.handled(), want)
+	}
+}
+
+func TestFlushWaitsForFinalSubscribers(t *testing.T) {
+	publisher := NewParkEventPublisher(context.Background())
+	publisher.Subscribe(&recordingSubscriber{})
+	final := newGatedSubscriber()
+	if err := publisher.SubscribeFinal(final, QueueOptions{}); err != nil {
+		t.Fatalf("SubscribeFinal: %v", err)
+	}
+	defer publisher.Close()
+
+	publisher.Publish(parkEvent(0))
+	final.waitStarted(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := publisher.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Flush returned %v while the final subscriber was busy, want it to wait", err)
+	}
+
+	close(final.gate)
+	if err := publisher.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+}
+
+func TestSecondCloseDoesNotHang(t *testing.T) {
+	publisher := NewParkEventPublisher(context.Background())
+	failing := errors.New("upsert failed")
+	publisher.Subscribe(&recordingSubscriber{err: failing})
+	publisher.Publish(parkEvent(0))
+
+	closed := make(chan [2]error, 1)
+	go func() {
+		first := publisher.Close()
+		closed <- [2]error{first, publisher.Close()}
+	}()
+
+	select {
+	case errs := <-closed:
+		if !errors.Is(errs[0], failing) || errs[1] != errs[0] {
+			t.Errorf("got errors %v and %v, want the same failure from both calls", errs[0], errs[1])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close hung")
+	}
+
+	// Events published after Close are dropped rather than blocking
+	publisher.Publish(parkEvent(1))
+}
+
+// recordingEventSubscriber records the types of the lifecycle events it handles and fails them with err
+type recordingEventSubscriber struct {
+	mu    sync.Mutex
+	types []EventType
+	err   error
+}
+
+func (r *recordingEventSubscriber) OnEvent(ctx context.Context, event Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types = append(r.types, event.Type())
+	return r.err
+}
+
+func (r *recordingEventSubscriber) handled() []EventType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]EventType(nil), r.types...)
+}
+
+func TestCloseJoinsSubscriberErrors(t *testing.T) {
+	publisher := NewParkEventPublisher(context.Background())
+	summaryErr := errors.New("summary unavailable")
+	dbErr := errors.New("database unavailable")
+	publisher.SubscribeEvents(&recordingEventSubscriber{err: summaryErr}, StateStartedType)
+	publisher.Subscribe(&recordingSubscriber{})
+	if err := publisher.SubscribeFinal(&recordingSubscriber{err: dbErr}, QueueOptions{Name: "journal"}); err != nil {
+		t.Fatalf("SubscribeFinal: %v", err)
+	}
+
+	publisher.Publish(StateStarted{StateCode: "IL"})
+	publisher.Publish(parkEvent(0))
+	err := publisher.Close()
+
+	if !errors.Is(err, summaryErr) || !errors.Is(err, dbErr) {
+		t.Errorf("got error %v, want both subscribers' failures", err)
+	}
+}
+
+// rejectingSubscriber fails the events of the URLs it holds and handles the others
+type rejectingSubscriber map[string]bool
+
+func (r rejectingSubscriber) OnParkScraped(ctx context.Context, event ParkScrapedEvent) error {
+	if r[event.URL] {
+		return errors.New("rejected")
+	}
+	return nil
+}
+
+func TestFinalSubscribersOnlyGetDeliveredEvents(t *testing.T) {
+	publisher := NewParkEventPublisher(context.Background())
+	publisher.Subscribe(&recordingSubscriber{})
+	publisher.Subscribe(rejectingSubscriber{parkURL(1): true})
+	// Holds one event while it handles another, and drops the older one for a newer
+	dropping := newGatedSubscriber()
+	if err := publisher.SubscribeWithOptions(dropping, QueueOptions{Size: 1, Overflow: OverflowDropOldest}); err != nil {
+		t.Fatalf("SubscribeWithOptions: %v", err)
+	}
+	final := &recordingSubscriber{}
+	if err := publisher.SubscribeFinal(final, QueueOptions{}); err != nil {
+		t.Fatalf("SubscribeFinal: %v", err)
+	}
+	defer publisher.Close()
+
+	publisher.Publish(parkEvent(0))
+	dropping.waitStarted(t)
+	for i := 1; i <= 3; i++ {
+		publisher.Publish(parkEvent(i))
+	}
+	close(dropping.gate)
+	if err := publisher.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	// 1 was rejected, 1 and 2 were dropped, so only 0 and 3 reached every subscriber
+	if want := parkURLs(0, 3); !reflect.DeepEqual(final.handled(), want) {
+		t.Errorf("the final subscriber handled %v, want %v", final.handled(), want)
+	}
+}
+
+// deferringSubscriber accepts events and hands their done functions out on a channel
+type deferringSubscriber struct {
+	dones chan func(delivered bool)
+}
+
+func (d *deferringSubscriber) OnParkScraped(ctx context.Context, event ParkScrapedEvent) error {
+	return errors.New("only deferred delivery is supported")
+}
+
+func (d *deferringSubscriber) OnParkScrapedDeferred(ctx context.Context, event ParkScrapedEvent, done func(delivered bool)) error {
+	d.dones <- done
+	return nil
+}
+
+func TestFinalSubscribersWaitForDeferredEvents(t *testing.T) {
+	publisher := NewParkEventPublisher(context.Background())
+	deferring := &deferringSubscriber{dones: make(chan func(delivered bool), 1)}
+	publisher.Subscribe(deferring)
+	final := &recordingSubscriber{}
+	if err := publisher.SubscribeFinal(final, QueueOptions{}); err != nil {
+		t.Fatalf("SubscribeFinal: %v", err)
+	}
+	defer publisher.Close()
+
+	publisher.Publish(parkEvent(0))
+	var done func(delivered bool)
+	select {
+	case done = <-deferring.dones:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the deferring subscriber never got the event")
+	}
+
+	// The subscriber accepted the event, but isn't done with it yet
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := publisher.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Flush returned %v before the deferred event was done", err)
+	}
+	if handled := final.handled(); len(handled) != 0 {
+		t.Fatalf("the final subscriber got %v before the deferred event was done", handled)
+	}
+
+	done(true)
+	if err := publisher.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if want := parkURLs(0); !reflect.DeepEqual(final.handled(), want) {
+		t.Errorf("the final subscriber handled %v, want %v", final.handled(), want)
+	}
+
+	// An event the subscriber gives up on doesn't reach the final subscriber
+	publisher.Publish(parkEvent(1))
+	select {
+	case done = <-deferring.dones:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the deferring subscriber never got the second event")
+	}
+	done(false)
+	if err := publisher.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if want := parkURLs(0); !reflect.DeepEqual(final.handled(), want) {
+		t.Errorf("the final subscriber handled %v, want only %v", final.handled(), want)
+	}
+}
+
+func TestSubscribeEventsFiltersByType(t *testing.T) {
+	tests := []struct {
+		name  string
+		types []EventType
+		want  []EventType
+	}{
+		{"only the given types", []EventType{StateStartedType, StateCompletedType},
+			[]EventType{StateStartedType, StateCompletedType}},
+		{"park events by type", []EventType{ParkScrapedType}, []EventType{ParkScrapedType}},
+		{"every type when none are given", nil,
+			[]EventType{RunStartedType, StateStartedType, ParkScrapedType, StateCompletedType, RunCompletedType}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			publisher := NewParkEventPublisher(context.Background())
+			subscriber := &recordingEventSubscriber{}
+			publisher.SubscribeEvents(subscriber, test.types...)
+
+			publisher.Publish(RunStarted{RunID: "run-1"})
+			publisher.Publish(StateStarted{StateCode: "IL"})
+			publisher.Publish(parkEvent(0))
+			publisher.Publish(StateCompleted{StateCode: "IL"})
+			publisher.Publish(RunCompleted{RunID: "run-1"})
+			if err := publisher.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			if got := subscriber.handled(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
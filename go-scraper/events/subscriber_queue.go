@@ -0,0 +1,446 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OverflowPolicy decides what a subscriber's queue does with a new event when it is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes Publish wait until the subscriber has room
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest queued event to make room for the new one
+	OverflowDropOldest
+	// OverflowSpillToDisk writes events to a file in QueueOptions.SpillDir until the subscriber catches up
+	OverflowSpillToDisk
+)
+
+// defaultQueueSize is how many events a subscriber's queue holds in memory unless configured otherwise
+const defaultQueueSize = 100
+
+var overflowPolicyNames = map[OverflowPolicy]string{
+	OverflowBlock:       "block",
+	OverflowDropOldest:  "drop-oldest",
+	OverflowSpillToDisk: "spill-to-disk",
+}
+
+func (o OverflowPolicy) String() string {
+	if name, ok := overflowPolicyNames[o]; ok {
+		return name
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(o))
+}
+
+// ParseOverflowPolicy parses "block", "drop-oldest" or "spill-to-disk"
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	for policy, policyName := range overflowPolicyNames {
+		if policyName == name {
+			return policy, nil
+		}
+	}
+	return OverflowBlock, fmt.Errorf("unknown overflow policy %q, expected block, drop-oldest or spill-to-disk", name)
+}
+
+// QueueOptions configures the queue in front of one subscriber
+type QueueOptions struct {
+	Name        string // Used in logs and for the spill and dead-letter files, defaults to the subscriber's type
+	Size        int    // Events held in memory, defaults to 100
+	Overflow    OverflowPolicy
+	SpillDir    string // Where OverflowSpillToDisk writes its file, required for that policy
+	Retry       RetryPolicy
+	DeadLetters *DeadLetterQueue // Where events go that still fail after every retry, nil to only report them from Close
+}
+
+// RetryPolicy decides how often a subscriber is given an event it returned an error for
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts per event including the first, 1 if not set
+	Backoff     time.Duration // Wait before the first retry, doubled for every further one
+	MaxBackoff  time.Duration // Cap on the wait between retries, none if not set
+}
+
+// queuedEvent is an event waiting for one subscriber
+type queuedEvent struct {
+	event Event
+	done  func(delivered bool) // Called once the subscriber is finished with the event, may be nil
+}
+
+// finish reports that the subscriber is finished with the event, and whether it handled it.
+// Events that failed, even if they were dead-lettered, or were dropped or lost weren't delivered.
+func (q queuedEvent) finish(delivered bool) {
+	if q.done != nil {
+		q.done(delivered)
+	}
+}
+
+// deliverFunc hands event to a subscriber. A subscriber that finishes events after it returns
+// calls done itself once it has, and deferred is true. Otherwise the queue calls done.
+type deliverFunc func(ctx context.Context, event Event, done func(delivered bool)) (deferred bool, err error)
+
+// subscriberQueue feeds events to a single subscriber on its own goroutine, so a slow or
+// panicking subscriber can't hold up or take down the others
+type subscriberQueue struct {
+	name     string
+	ctx      context.Context
+	overflow OverflowPolicy
+	deliver  deliverFunc
+	retry    RetryPolicy
+	dlq      *DeadLetterQueue
+	events   chan queuedEvent
+	spill    *spillFile // nil unless overflow is OverflowSpillToDisk
+
+	mu      sync.RWMutex // Keeps enqueue from sending on events after close
+	closed  bool
+	stopped chan struct{}
+	errs    []error // What went wrong while handling events, only touched by run
+}
+
+// newSubscriberQueue creates the queue for a subscriber and starts its goroutine. ctx is handed
+// to deliver with every event.
+func newSubscriberQueue(ctx context.Context, options QueueOptions, deliver deliverFunc) (*subscriberQueue, error) {
+	size := options.Size
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+
+	q := &subscriberQueue{
+		name:     options.Name,
+		ctx:      ctx,
+		overflow: options.Overflow,
+		deliver:  deliver,
+		retry:    options.Retry,
+		dlq:      options.DeadLetters,
+		events:   make(chan queuedEvent, size),
+		stopped:  make(chan struct{}),
+	}
+
+	switch options.Overflow {
+	case OverflowBlock, OverflowDropOldest:
+	case OverflowSpillToDisk:
+		if options.SpillDir == "" {
+			return nil, fmt.Errorf("queue of %s spills to disk but has no spill directory", options.Name)
+		}
+		spill, err := openSpillFile(filepath.Join(options.SpillDir, safeFileName(options.Name)+".jsonl"))
+		if err != nil {
+			return nil, fmt.Errorf("queue of %s: %w", options.Name, err)
+		}
+		q.spill = spill
+	default:
+		return nil, fmt.Errorf("queue of %s has unknown overflow policy %v", options.Name, options.Overflow)
+	}
+
+	go q.run()
+
+	return q, nil
+}
+
+// enqueue hands item to the subscriber, applying the overflow policy if the queue is full.
+// Items enqueued after close are dropped.
+func (q *subscriberQueue) enqueue(item queuedEvent) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		log.Printf("[EVENTS] Queue of %s is closed, dropping %s event", q.name, item.event.Type())
+		item.finish(false)
+		return
+	}
+
+	switch q.overflow {
+	case OverflowDropOldest:
+		q.enqueueDropOldest(item)
+	case OverflowSpillToDisk:
+		if !q.spill.push(q.name, item, q.events) {
+			// The event could not be spilled, so wait for room rather than lose it
+			q.events <- item
+		}
+	default:
+		q.events <- item
+	}
+}
+
+// enqueueDropOldest makes room for item by discarding the oldest queued events
+func (q *subscriberQueue) enqueueDropOldest(item queuedEvent) {
+	for {
+		select {
+		case q.events <- item:
+			return
+		default:
+		}
+
+		select {
+		case oldest := <-q.events:
+			log.Printf("[EVENTS] Queue of %s is full, dropping its oldest %s event", q.name, oldest.event.Type())
+			oldest.finish(false)
+		default:
+		}
+	}
+}
+
+// run delivers events until the queue is closed and empty
+func (q *subscriberQueue) run() {
+	defer close(q.stopped)
+
+	for {
+		// Spilled events are newer than those in memory, so they only come back once memory is empty
+		if q.spill != nil && len(q.events) == 0 {
+			if spilled := q.reclaimSpilled(); len(spilled) > 0 {
+				for _, item := range spilled {
+					q.handle(item)
+				}
+				continue
+			}
+		}
+
+		item, ok := <-q.events
+		if !ok {
+			break
+		}
+		q.handle(item)
+	}
+
+	if q.spill != nil {
+		for _, item := range q.reclaimSpilled() {
+			q.handle(item)
+		}
+		if err := q.spill.close(); err != nil {
+			q.fail(err)
+		}
+	}
+}
+
+// reclaimSpilled reads back the spilled events, recording any that were lost
+func (q *subscriberQueue) reclaimSpilled() []queuedEvent {
+	items, err := q.spill.reclaim()
+	if err != nil {
+		q.fail(fmt.Errorf("lost spilled events of %s: %w", q.name, err))
+	}
+	return items
+}
+
+// fail logs err and keeps it for close to return
+func (q *subscriberQueue) fail(err error) {
+	log.Printf("[EVENTS] %v", err)
+	q.errs = append(q.errs, err)
+}
+
+// handle delivers one event, retrying as the retry policy allows. An event that still fails
+// is dead-lettered if the queue has a dead-letter queue, and reported from close either way.
+func (q *subscriberQueue) handle(item queuedEvent) {
+	// A subscriber that panics after taking finish might still call it
+	var once sync.Once
+	finish := func(delivered bool) {
+		once.Do(func() { item.finish(delivered) })
+	}
+
+	attempts, deferred, err := q.deliverWithRetry(item.event, finish)
+	if deferred {
+		return
+	}
+	defer finish(err == nil)
+	if err == nil {
+		return
+	}
+
+	failure := fmt.Errorf("subscriber %s failed to handle %s event after %d attempts: %w", q.name, item.event.Type(), attempts, err)
+	if q.dlq == nil {
+		q.fail(failure)
+		return
+	}
+
+	dlqErr := q.dlq.Add(DeadLetter{
+		Subscriber: q.name,
+		Event:      item.event,
+		Err:        err.Error(),
+		Attempts:   attempts,
+		FailedAt:   time.Now(),
+	})
+	if dlqErr != nil {
+		q.fail(fmt.Errorf("%w: %w: %v", failure, ErrNotDeadLettered, dlqErr))
+		return
+	}
+	q.fail(fmt.Errorf("%w (dead-lettered to %s)", failure, q.dlq.Path(q.name)))
+}
+
+// deliverWithRetry delivers event until the subscriber accepts it, the retry policy gives up
+// or ctx is done, and returns how many attempts that took, whether the subscriber took over
+// calling done and the last error
+func (q *subscriberQueue) deliverWithRetry(event Event, done func(delivered bool)) (int, bool, error) {
+	maxAttempts := max(q.retry.MaxAttempts, 1)
+	backoff := q.retry.Backoff
+
+	for attempt := 1; ; attempt++ {
+		deferred, err := q.attempt(event, done)
+		if err == nil || attempt >= maxAttempts || q.ctx.Err() != nil {
+			return attempt, deferred, err
+		}
+
+		log.Printf("[EVENTS] Subscriber %s failed to handle %s event (attempt %d/%d), retrying in %v: %v", q.name, event.Type(), attempt, maxAttempts, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-q.ctx.Done():
+			return attempt, false, err
+		}
+
+		backoff *= 2
+		if q.retry.MaxBackoff > 0 {
+			backoff = min(backoff, q.retry.MaxBackoff)
+		}
+	}
+}
+
+// attempt delivers event once. A panic in the subscriber is turned into an error instead of crashing the process.
+func (q *subscriberQueue) attempt(event Event, done func(delivered bool)) (deferred bool, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("[EVENTS] Subscriber %s panicked handling %s event: %v\n%s", q.name, event.Type(), recovered, debug.Stack())
+			deferred, err = false, fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	return q.deliver(q.ctx, event, done)
+}
+
+// close stops accepting events, blocks until the subscriber has been handed every queued one
+// and returns what went wrong while handling them
+func (q *subscriberQueue) close() error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.events)
+	}
+	q.mu.Unlock()
+
+	<-q.stopped
+	return errors.Join(q.errs...)
+}
+
+// spillFile holds the events that did not fit into a queue's memory, oldest first
+type spillFile struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	dones []func(delivered bool) // done of each spilled event, in file order
+}
+
+// openSpillFile creates an empty spill file at path, discarding whatever an earlier run left there
+func openSpillFile(path string) (*spillFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spill file: %w", err)
+	}
+
+	return &spillFile{path: path, file: file}, nil
+}
+
+// push sends item to events if nothing is spilled yet and there is room, and spills it otherwise,
+// so events keep their order. It returns false if item could be neither sent nor spilled.
+func (s *spillFile) push(name string, item queuedEvent, events chan<- queuedEvent) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.dones) == 0 {
+		select {
+		case events <- item:
+			return true
+		default:
+			log.Printf("[EVENTS] Queue of %s is full, spilling events to %s", name, s.path)
+		}
+	}
+
+	data, err := encodeEvent(item.event)
+	if err == nil {
+		_, err = s.file.Write(append(data, '\n'))
+	}
+	if err != nil {
+		log.Printf("[EVENTS] Failed to spill %s event for %s: %v", item.event.Type(), name, err)
+		return false
+	}
+
+	s.dones = append(s.dones, item.done)
+	return true
+}
+
+// reclaim reads back every spilled event in order and empties the file. Events that can't be
+// read back are finished as not delivered, and reported in the error.
+func (s *spillFile) reclaim() ([]queuedEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.dones) == 0 {
+		return nil, nil
+	}
+
+	var errs []error
+	items := make([]queuedEvent, 0, len(s.dones))
+	read := 0
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		errs = append(errs, fmt.Errorf("failed to read spill file %s: %w", s.path, err))
+	} else {
+		decoder := json.NewDecoder(s.file)
+		for ; read < len(s.dones); read++ {
+			var data json.RawMessage
+			if err := decoder.Decode(&data); err != nil {
+				errs = append(errs, fmt.Errorf("failed to read spill file %s: %w", s.path, err))
+				break
+			}
+
+			event, err := decodeEvent(data)
+			if err != nil {
+				errs = append(errs, err)
+				queuedEvent{done: s.dones[read]}.finish(false)
+				continue
+			}
+			items = append(items, queuedEvent{event: event, done: s.dones[read]})
+		}
+	}
+
+	if unread := len(s.dones) - read; unread > 0 {
+		errs = append(errs, fmt.Errorf("%d events could not be read back", unread))
+		for _, done := range s.dones[read:] {
+			queuedEvent{done: done}.finish(false)
+		}
+	}
+	s.dones = nil
+
+	err := s.file.Truncate(0)
+	if err == nil {
+		_, err = s.file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		log.Printf("[EVENTS] Failed to empty spill file %s: %v", s.path, err)
+	}
+
+	return items, errors.Join(errs...)
+}
+
+// close closes and removes the file
+func (s *spillFile) close() error {
+	if err := errors.Join(s.file.Close(), os.Remove(s.path)); err != nil {
+		return fmt.Errorf("failed to remove spill file %s: %w", s.path, err)
+	}
+	return nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// safeFileName turns a subscriber name like "*writers.APIParkWriter" into a file name without extension
+func safeFileName(name string) string {
+	return strings.Trim(unsafeFileChars.ReplaceAllString(name, "_"), "_.")
+}
@@ -0,0 +1,211 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSubscriber records the URLs of the events it handles. If gate is set it
+// signals started on its first event and then waits for gate to close before every event.
+type recordingSubscriber struct {
+	mu      sync.Mutex
+	urls    []string
+	gate    chan struct{}
+	started chan struct{}
+	once    sync.Once
+	err     error
+}
+
+func newGatedSubscriber() *recordingSubscriber {
+	return &recordingSubscriber{gate: make(chan struct{}), started: make(chan struct{})}
+}
+
+func (r *recordingSubscriber) OnParkScraped(ctx context.Context, event ParkScrapedEvent) error {
+	if r.gate != nil {
+		r.once.Do(func() { close(r.started) })
+		<-r.gate
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.urls = append(r.urls, event.URL)
+	return r.err
+}
+
+func (r *recordingSubscriber) handled() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.urls...)
+}
+
+// waitStarted waits until the subscriber is blocked on its first event
+func (r *recordingSubscriber) waitStarted(t *testing.T) {
+	t.Helper()
+
+	select {
+	case <-r.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the subscriber never got its first event")
+	}
+}
+
+// panickingSubscriber panics on every event
+type panickingSubscriber struct{}
+
+func (panickingSubscriber) OnParkScraped(ctx context.Context, event ParkScrapedEvent) error {
+	panic("subscriber bug")
+}
+
+func parkEvent(i int) ParkScrapedEvent {
+	return ParkScrapedEvent{URL: parkURL(i), StateCode: "IL"}
+}
+
+func parkURL(i int) string {
+	return fmt.Sprintf("https://dnr.illinois.gov/parks/park/%d", i)
+}
+
+func parkURLs(indexes ...int) []string {
+	urls := make([]string, len(indexes))
+	for i, index := range indexes {
+		urls[i] = parkURL(index)
+	}
+	return urls
+}
+
+func TestPanickingSubscriberDoesNotStopOthers(t *testing.T) {
+	publisher := NewParkEventPublisher(context.Background())
+	publisher.Subscribe(panickingSubscriber{})
+	recorder := &recordingSubscriber{}
+	publisher.Subscribe(recorder)
+
+	for i := range 3 {
+		publisher.Publish(parkEvent(i))
+	}
+	if err := publisher.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	if want := parkURLs(0, 1, 2); !reflect.DeepEqual(recorder.handled(), want) {
+		t.Errorf("the other subscriber handled %v, want %v", recorder.handled(), want)
+	}
+
+	err := publisher.Close()
+	if err == nil || !strings.Contains(err.Error(), "panic: subscriber bug") {
+		t.Errorf("got error %v, want the panics reported", err)
+	}
+}
+
+func TestDropOldestKeepsNewestEvents(t *testing.T) {
+	publisher := NewParkEventPublisher(context.Background())
+	subscriber := newGatedSubscriber()
+	if err := publisher.SubscribeWithOptions(subscriber, QueueOptions{Size: 3, Overflow: OverflowDropOldest}); err != nil {
+		t.Fatalf("SubscribeWithOptions: %v", err)
+	}
+
+	// The first event is being handled, so the queue fills up behind it
+	publisher.Publish(parkEvent(0))
+	subscriber.waitStarted(t)
+	for i := 1; i <= 10; i++ {
+		publisher.Publish(parkEvent(i))
+	}
+
+	close(subscriber.gate)
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if want := parkURLs(0, 8, 9, 10); !reflect.DeepEqual(subscriber.handled(), want) {
+		t.Errorf("handled %v, want %v", subscriber.handled(), want)
+	}
+}
+
+func TestSpilledEventsComeBackInOrder(t *testing.T) {
+	spillDir := t.TempDir()
+	publisher := NewParkEventPublisher(context.Background())
+	subscriber := newGatedSubscriber()
+	options := QueueOptions{Name: "slow", Size: 2, Overflow: OverflowSpillToDisk, SpillDir: spillDir}
+	if err := publisher.SubscribeWithOptions(subscriber, options); err != nil {
+		t.Fatalf("SubscribeWithOptions: %v", err)
+	}
+
+	publisher.Publish(parkEvent(0))
+	subscriber.waitStarted(t)
+	want := []string{parkURL(0)}
+	for i := 1; i <= 20; i++ {
+		publisher.Publish(parkEvent(i))
+		want = append(want, parkURL(i))
+	}
+
+	close(subscriber.gate)
+	if err := publisher.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !reflect.DeepEqual(subscriber.handled(), want) {
+		t.Errorf("handled %v, want %v", subscriber.handled(), want)
+	}
+
+	// The spill file is removed once the queue is closed
+	entries, err := os.ReadDir(spillDir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("spill directory still holds %v", entries)
+	}
+}
+
+func TestSlowSubscriberDoesNotDelayFastOne(t *testing.T) {
+	publisher := NewParkEventPublisher(context.Background())
+	slow := newGatedSubscriber()
+	publisher.Subscribe(slow)
+	fast := &recordingSubscriber{}
+	publisher.Subscribe(fast)
+
+	for i := range 10 {
+		publisher.Publish(parkEvent(i))
+	}
+	slow.waitStarted(t)
+
+	// The fast subscriber gets through every event while the slow one is stuck on its first
+	deadline := time.Now().Add(5 * time.Second)
+	for len(fast.handled()) < 10 {
+		if time.Now().After(deadline) {
+			t.Fatalf("the fast subscriber handled only %d of 10 events while the slow one was busy", len(fast.handled()))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if handled := slow.handled(); len(handled) != 0 {
+		t.Errorf("the slow subscriber handled %v before it was released", handled)
+	}
+
+	close(slow.gate)
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if len(slow.handled()) != 10 {
+		t.Errorf("the slow subscriber handled %d of 10 events", len(slow.handled()))
+	}
+}
+
+func TestOverflowPolicyRoundTrips(t *testing.T) {
+	for _, policy := range []OverflowPolicy{OverflowBlock, OverflowDropOldest, OverflowSpillToDisk} {
+		parsed, err := ParseOverflowPolicy(policy.String())
+		if err != nil || parsed != policy {
+			t.Errorf("ParseOverflowPolicy(%q) = %v, %v, want %v", policy.String(), parsed, err, policy)
+		}
+	}
+	if _, err := ParseOverflowPolicy("drop-newest"); err == nil {
+		t.Error("ParseOverflowPolicy accepted an unknown policy")
+	}
+	if err := NewParkEventPublisher(context.Background()).SubscribeWithOptions(&recordingSubscriber{}, QueueOptions{Overflow: OverflowSpillToDisk}); err == nil {
+		t.Error("a spilling queue without a spill directory was accepted")
+	}
+}
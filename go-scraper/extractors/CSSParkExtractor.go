@@ -0,0 +1,226 @@
+package extractors
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"scraper/configHelper"
+	"scraper/models"
+	"scraper/services"
+	"strings"
+
+	"github.com/gocolly/colly"
+)
+
+// CSSParkExtractor reads parks using selectors declared in urls.json instead of Go code
+type CSSParkExtractor struct {
+	stateCode  string
+	name       fieldSelector
+	latitude   fieldSelector
+	longitude  fieldSelector
+	address    fieldSelector
+	activities fieldSelector
+	geocoder   *services.GeocodingService
+}
+
+// fieldSelector is a compiled configHelper.SelectorConfig
+type fieldSelector struct {
+	selector string
+	attr     string
+	regex    *regexp.Regexp
+}
+
+// NewCSSParkExtractor builds an extractor for stateCode from its selector config.
+// geocoder may be nil, in which case parks without coordinates on the page are dropped.
+// Fields the selectors don't find are taken from the page's schema.org markup, if any.
+func NewCSSParkExtractor(stateCode string, config configHelper.ExtractorConfig, geocoder *services.GeocodingService) (*CSSParkExtractor, error) {
+	if config.Type != "" && config.Type != "css" {
+		return nil, fmt.Errorf("extractor for %s has unknown type %q", stateCode, config.Type)
+	}
+	if config.Name.Selector == "" {
+		return nil, fmt.Errorf("extractor for %s has no name selector", stateCode)
+	}
+
+	extractor := &CSSParkExtractor{
+		stateCode: stateCode,
+		geocoder:  geocoder,
+	}
+
+	fields := []struct {
+		label  string
+		config configHelper.SelectorConfig
+		target *fieldSelector
+	}{
+		{"name", config.Name, &extractor.name},
+		{"latitude", config.Latitude, &extractor.latitude},
+		{"longitude", config.Longitude, &extractor.longitude},
+		{"address", config.Address, &extractor.address},
+		{"activities", config.Activities, &extractor.activities},
+	}
+
+	for _, field := range fields {
+		compiled, err := compileSelector(field.config)
+		if err != nil {
+			return nil, fmt.Errorf("extractor for %s has an invalid %s selector: %w", stateCode, field.label, err)
+		}
+		*field.target = compiled
+	}
+
+	return extractor, nil
+}
+
+// compileSelector validates a selector config and compiles its regex
+func compileSelector(config configHelper.SelectorConfig) (fieldSelector, error) {
+	selector := fieldSelector{
+		selector: config.Selector,
+		attr:     config.Attr,
+	}
+
+	if config.Regex != "" {
+		if config.Selector == "" {
+			return selector, fmt.Errorf("regex %q has no selector to apply to", config.Regex)
+		}
+
+		regex, err := regexp.Compile(config.Regex)
+		if err != nil {
+			return selector, err
+		}
+		selector.regex = regex
+	}
+
+	return selector, nil
+}
+
+func (s *CSSParkExtractor) ExtractParkData(ctx context.Context, e *colly.HTMLElement) (*models.Park, []string, error) {
+	issues := &extractionIssues{}
+
+	// schema.org markup fills in whatever the selectors don't find
+	place, hasPlace := ExtractSchemaOrgPlace(e)
+
+	parkName := s.name.first(e)
+	if parkName == "" {
+		parkName = place.Name
+	}
+	if parkName == "" {
+		issues.missingField("name")
+	}
+
+	address := s.address.first(e)
+	if address == "" {
+		address = place.Address
+	}
+
+	latitudeStr := s.latitude.first(e)
+	longitudeStr := s.longitude.first(e)
+	latitude, errLat := parseCoordinate(latitudeStr)
+	longitude, errLon := parseCoordinate(longitudeStr)
+
+	// Fall back to declared coordinates, then to geocoding the address, when the selectors
+	// give no usable coordinates
+	if errLat != nil || errLon != nil {
+		if s.latitude.selector != "" {
+			issues.warn("coordinates %q, %q could not be read from the page", latitudeStr, longitudeStr)
+		}
+
+		latitude, longitude = 0, 0
+		switch {
+		case hasPlace && place.HasGeo:
+			latitude, longitude = place.Latitude, place.Longitude
+		case address != "" && s.geocoder != nil:
+			coords, err := s.geocoder.GeocodeAddress(ctx, address)
+			if err != nil {
+				issues.warn("geocoding %q failed: %v", address, err)
+				issues.missingField("coordinates")
+			} else {
+				latitude, longitude = coords.Latitude, coords.Longitude
+			}
+		default:
+			if errLat != nil {
+				issues.coordinateField("latitude", latitudeStr)
+			}
+			if errLon != nil {
+				issues.coordinateField("longitude", longitudeStr)
+			}
+		}
+	}
+
+	activities := []models.ParkActivity{}
+	for _, activityName := range s.activities.all(e) {
+		activities = append(activities, models.ParkActivity{
+			Name:        activityName,
+			Description: "",
+		})
+	}
+
+	park := &models.Park{
+		Name:       parkName,
+		StateCode:  s.stateCode,
+		Address:    address,
+		Latitude:   latitude,
+		Longitude:  longitude,
+		Activities: activities,
+	}
+	if hasPlace {
+		place.fillMissing(park)
+	}
+	if err := issues.err(); err != nil {
+		return park, nil, err
+	}
+	if len(park.Activities) == 0 {
+		issues.warn("no activities found")
+	}
+
+	return park, issues.warnings, nil
+}
+
+// first returns the value of the first matching element, or "" if the selector is unset or matches nothing
+func (f fieldSelector) first(e *colly.HTMLElement) string {
+	if f.selector == "" {
+		return ""
+	}
+
+	var value string
+	e.ForEachWithBreak(f.selector, func(_ int, el *colly.HTMLElement) bool {
+		value = f.value(el)
+		return false
+	})
+	return value
+}
+
+// all returns the non-empty values of every matching element
+func (f fieldSelector) all(e *colly.HTMLElement) []string {
+	values := []string{}
+	if f.selector == "" {
+		return values
+	}
+
+	e.ForEach(f.selector, func(_ int, el *colly.HTMLElement) {
+		if value := f.value(el); value != "" {
+			values = append(values, value)
+		}
+	})
+	return values
+}
+
+// value reads el's text or attribute, collapses whitespace and applies the regex
+func (f fieldSelector) value(el *colly.HTMLElement) string {
+	raw := el.Text
+	if f.attr != "" {
+		raw = el.Attr(f.attr)
+	}
+	value := strings.Join(strings.Fields(raw), " ")
+
+	if f.regex == nil {
+		return value
+	}
+
+	match := f.regex.FindStringSubmatch(value)
+	switch {
+	case match == nil:
+		return ""
+	case len(match) > 1:
+		return strings.TrimSpace(match[1])
+	default:
+		return strings.TrimSpace(match[0])
+	}
+}
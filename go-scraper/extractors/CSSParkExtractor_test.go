@@ -0,0 +1,156 @@
+package extractors
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"scraper/configHelper"
+	"scraper/models"
+	"scraper/services"
+	"strings"
+	"testing"
+
+	"github.com/gocolly/colly"
+)
+
+// extractFixture serves testdata/<name> and runs extractor on it the way BaseParkScraper does
+func extractFixture(t *testing.T, extractor ParkExtractor, name string) (*models.Park, []string, error) {
+	t.Helper()
+
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer server.Close()
+
+	var (
+		park     *models.Park
+		warnings []string
+		err      error
+		visited  bool
+	)
+	c := colly.NewCollector()
+	c.OnHTML("html", func(e *colly.HTMLElement) {
+		visited = true
+		park, warnings, err = extractor.ExtractParkData(context.Background(), e)
+	})
+	if visitErr := c.Visit(server.URL + "/" + name); visitErr != nil {
+		t.Fatalf("failed to load %s: %v", name, visitErr)
+	}
+	if !visited {
+		t.Fatalf("%s has no html element", name)
+	}
+	return park, warnings, err
+}
+
+// roundTripFunc answers requests with a function, e.g. to stand in for the geocoding API
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// stubGeocoder returns a geocoder whose API always answers with body
+func stubGeocoder(body string) *services.GeocodingService {
+	return services.NewGeocodingServiceWithClient("test-key", &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Request:    req,
+			}, nil
+		}),
+	})
+}
+
+// gpsConfig reads both coordinates out of one "GPS: 43.6512 N, -88.1904 W" line with regexes
+var gpsConfig = configHelper.ExtractorConfig{
+	Name:       configHelper.SelectorConfig{Selector: "h1.park-title"},
+	Latitude:   configHelper.SelectorConfig{Selector: "p.gps", Regex: `GPS:\s*(-?[\d.]+)`},
+	Longitude:  configHelper.SelectorConfig{Selector: "p.gps", Regex: `,\s*(-?[\d.]+)`},
+	Address:    configHelper.SelectorConfig{Selector: "p.address", Regex: `Address:\s*(.+)`},
+	Activities: configHelper.SelectorConfig{Selector: "ul.park-activities li span"},
+}
+
+func TestCSSParkExtractorAppliesRegexes(t *testing.T) {
+	extractor, err := NewCSSParkExtractor("WI", gpsConfig, nil)
+	if err != nil {
+		t.Fatalf("NewCSSParkExtractor: %v", err)
+	}
+
+	park, warnings, err := extractFixture(t, extractor, "css_park.html")
+	if err != nil {
+		t.Fatalf("extraction failed: %v", err)
+	}
+
+	want := &models.Park{
+		Name:      "Kettle Moraine State Forest",
+		StateCode: "WI",
+		Address:   "N1765 County Road G, Campbellsport, WI 53010",
+		Latitude:  43.6512,
+		Longitude: -88.1904,
+		Activities: []models.ParkActivity{
+			{Name: "Camping"},
+			{Name: "Hiking"},
+			{Name: "Cross-country skiing"},
+		},
+	}
+	if !reflect.DeepEqual(park, want) {
+		t.Errorf("got park %+v, want %+v", park, want)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("got warnings %v, want none", warnings)
+	}
+}
+
+func TestCSSParkExtractorGeocodesWhenCoordinatesDontParse(t *testing.T) {
+	geocoder := stubGeocoder(`{"features":[{"center":[-89.7296,43.4130]}]}`)
+	extractor, err := NewCSSParkExtractor("WI", gpsConfig, geocoder)
+	if err != nil {
+		t.Fatalf("NewCSSParkExtractor: %v", err)
+	}
+
+	park, warnings, err := extractFixture(t, extractor, "css_park_no_coordinates.html")
+	if err != nil {
+		t.Fatalf("extraction failed: %v", err)
+	}
+	if park.Latitude != 43.4130 || park.Longitude != -89.7296 {
+		t.Errorf("got coordinates (%v, %v), want the geocoded (43.4130, -89.7296)", park.Latitude, park.Longitude)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("got warnings %v, want one about the unreadable coordinates", warnings)
+	}
+}
+
+func TestCSSParkExtractorReportsFailedGeocode(t *testing.T) {
+	extractor, err := NewCSSParkExtractor("WI", gpsConfig, stubGeocoder(`{"features":[]}`))
+	if err != nil {
+		t.Fatalf("NewCSSParkExtractor: %v", err)
+	}
+
+	park, _, err := extractFixture(t, extractor, "css_park_no_coordinates.html")
+	var extractionErr *ExtractionError
+	if !errors.As(err, &extractionErr) {
+		t.Fatalf("got park %+v and error %v, want an *ExtractionError", park, err)
+	}
+	if !reflect.DeepEqual(extractionErr.Missing, []string{"coordinates"}) {
+		t.Errorf("got missing fields %v, want [coordinates]", extractionErr.Missing)
+	}
+	if len(extractionErr.Warnings) != 2 {
+		t.Errorf("got warnings %v, want the unreadable coordinates and the failed geocode", extractionErr.Warnings)
+	}
+}
+
+func TestNewCSSParkExtractorRejectsBadRegex(t *testing.T) {
+	config := gpsConfig
+	config.Latitude = configHelper.SelectorConfig{Selector: "p.gps", Regex: `GPS:\s*(`}
+	if _, err := NewCSSParkExtractor("WI", config, nil); err == nil {
+		t.Error("NewCSSParkExtractor accepted an invalid regex")
+	}
+
+	config.Latitude = configHelper.SelectorConfig{Regex: `GPS:\s*(-?[\d.]+)`}
+	if _, err := NewCSSParkExtractor("WI", config, nil); err == nil {
+		t.Error("NewCSSParkExtractor accepted a regex without a selector")
+	}
+}
@@ -0,0 +1,126 @@
+package extractors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"scraper/models"
+
+	"github.com/gocolly/colly"
+)
+
+// ExtractorLayer is one extractor of a CompositeParkExtractor, with the name used to
+// record which fields it supplied
+type ExtractorLayer struct {
+	Name      string
+	Extractor ParkExtractor
+}
+
+// CompositeParkExtractor runs several extractors in priority order and merges their parks.
+// For every field the first layer with a non-empty value wins, and the park's FieldSources
+// records which layer that was. A layer that fails with an *ExtractionError still contributes
+// the fields of its partial park. Layers that implement ParkFiller get the park merged so far.
+type CompositeParkExtractor struct {
+	layers []ExtractorLayer
+}
+
+// NewCompositeParkExtractor creates an extractor that merges layers, highest priority first
+func NewCompositeParkExtractor(layers ...ExtractorLayer) *CompositeParkExtractor {
+	return &CompositeParkExtractor{
+		layers: layers,
+	}
+}
+
+func (c *CompositeParkExtractor) ExtractParkData(ctx context.Context, e *colly.HTMLElement) (*models.Park, []string, error) {
+	issues := &extractionIssues{}
+	merged := &models.Park{
+		Activities:   []models.ParkActivity{},
+		FieldSources: make(map[string]string),
+	}
+
+	// What went wrong in each layer only matters if the merged park is unusable
+	var layerProblems []string
+
+	for _, layer := range c.layers {
+		// Lower layers may be expensive (geocoding), so stop once nothing is left to fill
+		if parkComplete(merged) {
+			break
+		}
+
+		var park *models.Park
+		var warnings []string
+		var err error
+		if filler, ok := layer.Extractor.(ParkFiller); ok {
+			park, warnings, err = filler.FillPark(ctx, e, *merged)
+		} else {
+			park, warnings, err = layer.Extractor.ExtractParkData(ctx, e)
+		}
+		if err != nil {
+			layerProblems = append(layerProblems, fmt.Sprintf("%s: %v", layer.Name, err))
+
+			// A partial park still fills fields. What the layer lacked may come from the layers below,
+			// so only its warnings are kept.
+			var extractionErr *ExtractionError
+			if errors.As(err, &extractionErr) && park != nil && mergePark(merged, park, layer.Name) {
+				for _, warning := range extractionErr.Warnings {
+					issues.warn("%s: %s", layer.Name, warning)
+				}
+			}
+			continue
+		}
+
+		if mergePark(merged, park, layer.Name) {
+			for _, warning := range warnings {
+				issues.warn("%s: %s", layer.Name, warning)
+			}
+		}
+	}
+
+	if merged.Name == "" {
+		issues.missingField("name")
+	}
+	if merged.FieldSources["coordinates"] == "" {
+		issues.missingField("coordinates")
+	}
+	if len(issues.missing) > 0 {
+		issues.warnings = append(issues.warnings, layerProblems...)
+		return merged, nil, issues.err()
+	}
+
+	return merged, issues.warnings, nil
+}
+
+// mergePark copies the fields of park that merged doesn't have yet, crediting them to source.
+// It reports whether any field was taken.
+func mergePark(merged *models.Park, park *models.Park, source string) bool {
+	took := false
+	take := func(field string, empty bool, set func()) {
+		if merged.FieldSources[field] == "" && !empty {
+			set()
+			merged.FieldSources[field] = source
+			took = true
+		}
+	}
+
+	take("name", park.Name == "", func() { merged.Name = park.Name })
+	take("stateCode", park.StateCode == "", func() { merged.StateCode = park.StateCode })
+	take("address", park.Address == "", func() { merged.Address = park.Address })
+	take("telephone", park.Telephone == "", func() { merged.Telephone = park.Telephone })
+	take("openingHours", len(park.OpeningHours) == 0, func() { merged.OpeningHours = park.OpeningHours })
+	take("activities", len(park.Activities) == 0, func() { merged.Activities = park.Activities })
+	take("coordinates", park.Latitude == 0 && park.Longitude == 0, func() {
+		merged.Latitude = park.Latitude
+		merged.Longitude = park.Longitude
+	})
+	return took
+}
+
+// parkComplete reports whether every field merged from the layers has a value
+func parkComplete(park *models.Park) bool {
+	for _, field := range []string{"name", "stateCode", "address", "telephone", "openingHours", "activities", "coordinates"} {
+		if park.FieldSources[field] == "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package extractors
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"scraper/models"
+	"testing"
+
+	"github.com/gocolly/colly"
+)
+
+// stubExtractor returns the same result for every page and counts its calls
+type stubExtractor struct {
+	park     *models.Park
+	warnings []string
+	err      error
+	calls    int
+}
+
+func (s *stubExtractor) ExtractParkData(ctx context.Context, e *colly.HTMLElement) (*models.Park, []string, error) {
+	s.calls++
+	return s.park, s.warnings, s.err
+}
+
+func TestCompositeMergesPartialParks(t *testing.T) {
+	// The first layer finds the name and activities but no coordinates
+	partial := &stubExtractor{
+		park: &models.Park{
+			Name:       "Kettle Moraine State Forest",
+			StateCode:  "WI",
+			Activities: []models.ParkActivity{{Name: "Hiking"}},
+		},
+		err: &ExtractionError{Missing: []string{"latitude", "longitude"}, Warnings: []string{"no telephone listed"}},
+	}
+	// A failure that isn't an *ExtractionError contributes nothing, even with a park
+	broken := &stubExtractor{
+		park: &models.Park{Name: "Wrong Name", Telephone: "000"},
+		err:  errors.New("page layout changed"),
+	}
+	located := &stubExtractor{
+		park:     &models.Park{Name: "Kettle Moraine", StateCode: "WI", Address: "N1765 County Road G", Latitude: 43.6512, Longitude: -88.1904},
+		warnings: []string{"address geocoded"},
+	}
+
+	composite := NewCompositeParkExtractor(
+		ExtractorLayer{Name: "css", Extractor: partial},
+		ExtractorLayer{Name: "builtin", Extractor: broken},
+		ExtractorLayer{Name: "geocode", Extractor: located},
+	)
+	park, warnings, err := extractFixture(t, composite, "css_park.html")
+	if err != nil {
+		t.Fatalf("extraction failed: %v", err)
+	}
+
+	want := &models.Park{
+		Name:       "Kettle Moraine State Forest",
+		StateCode:  "WI",
+		Address:    "N1765 County Road G",
+		Latitude:   43.6512,
+		Longitude:  -88.1904,
+		Activities: []models.ParkActivity{{Name: "Hiking"}},
+		FieldSources: map[string]string{
+			"name":        "css",
+			"stateCode":   "css",
+			"activities":  "css",
+			"address":     "geocode",
+			"coordinates": "geocode",
+		},
+	}
+	if !reflect.DeepEqual(park, want) {
+		t.Errorf("got park %+v, want %+v", park, want)
+	}
+	// What the css layer lacked came from below, so only its own warning is kept
+	wantWarnings := []string{
+		"css: no telephone listed",
+		"geocode: address geocoded",
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("got warnings %q, want %q", warnings, wantWarnings)
+	}
+}
+
+func TestCompositeReturnsPartialParkWhenStillIncomplete(t *testing.T) {
+	partial := &stubExtractor{
+		park: &models.Park{Name: "Volo Bog State Natural Area", StateCode: "IL"},
+		err:  &ExtractionError{Missing: []string{"geo"}},
+	}
+	nothing := &stubExtractor{err: &ExtractionError{Missing: []string{"name"}}}
+
+	composite := NewCompositeParkExtractor(
+		ExtractorLayer{Name: "schema-org", Extractor: partial},
+		ExtractorLayer{Name: "css", Extractor: nothing},
+	)
+	park, _, err := extractFixture(t, composite, "css_park.html")
+
+	var extractionErr *ExtractionError
+	if !errors.As(err, &extractionErr) {
+		t.Fatalf("got error %v, want an *ExtractionError", err)
+	}
+	if !reflect.DeepEqual(extractionErr.Missing, []string{"coordinates"}) {
+		t.Errorf("got missing fields %v, want [coordinates]", extractionErr.Missing)
+	}
+	wantWarnings := []string{"schema-org: " + partial.err.Error(), "css: " + nothing.err.Error()}
+	if !reflect.DeepEqual(extractionErr.Warnings, wantWarnings) {
+		t.Errorf("got warnings %q, want %q", extractionErr.Warnings, wantWarnings)
+	}
+	if park == nil || park.Name != "Volo Bog State Natural Area" || park.FieldSources["name"] != "schema-org" {
+		t.Errorf("got park %+v, want the partial park merged so far", park)
+	}
+}
+
+func TestCompositeStopsOnceParkIsComplete(t *testing.T) {
+	complete := &stubExtractor{park: &models.Park{
+		Name:         "Starved Rock State Park",
+		StateCode:    "IL",
+		Address:      "2678 E 875th Rd, Oglesby, IL 61348",
+		Telephone:    "815-667-4726",
+		OpeningHours: []string{"Mo-Su 07:00-21:00"},
+		Latitude:     41.3189,
+		Longitude:    -88.9909,
+		Activities:   []models.ParkActivity{{Name: "Hiking"}},
+	}}
+	expensive := &stubExtractor{park: &models.Park{Latitude: 1, Longitude: 1}}
+
+	composite := NewCompositeParkExtractor(
+		ExtractorLayer{Name: "schema-org", Extractor: complete},
+		ExtractorLayer{Name: "geocode", Extractor: expensive},
+	)
+	if _, _, err := extractFixture(t, composite, "css_park.html"); err != nil {
+		t.Fatalf("extraction failed: %v", err)
+	}
+	if expensive.calls != 0 {
+		t.Errorf("the lower layer ran %d times after the park was complete", expensive.calls)
+	}
+}
+
+func TestExtractorsReturnPartialParks(t *testing.T) {
+	// The page's "GPS: coming soon" can't be read, and there is no geocoder to fall back on
+	extractor, err := NewCSSParkExtractor("WI", gpsConfig, nil)
+	if err != nil {
+		t.Fatalf("NewCSSParkExtractor: %v", err)
+	}
+
+	park, _, err := extractFixture(t, extractor, "css_park_no_coordinates.html")
+	var extractionErr *ExtractionError
+	if !errors.As(err, &extractionErr) {
+		t.Fatalf("got error %v, want an *ExtractionError", err)
+	}
+	if park == nil || park.Name != "Devil's Lake State Park" || park.Address != "S5975 Park Rd, Baraboo, WI 53913" {
+		t.Fatalf("got park %+v, want the name and address that were found", park)
+	}
+	if park.Latitude != 0 || park.Longitude != 0 {
+		t.Errorf("got coordinates (%v, %v) on a park whose coordinates weren't found", park.Latitude, park.Longitude)
+	}
+}
@@ -0,0 +1,87 @@
+package extractors
+
+import (
+	"context"
+	"fmt"
+	"scraper/configHelper"
+	"scraper/models"
+	"scraper/services"
+
+	"github.com/gocolly/colly"
+)
+
+// ParkFiller is implemented by extractors that fill in a park from what the layers above them
+// in a CompositeParkExtractor found, rather than from the page alone
+type ParkFiller interface {
+	// FillPark returns the fields it adds to merged, the park the layers above found so far.
+	// Errors follow the ExtractParkData contract.
+	FillPark(ctx context.Context, e *colly.HTMLElement, merged models.Park) (park *models.Park, warnings []string, err error)
+}
+
+// GeocodeParkExtractor supplies the coordinates of a park by geocoding its address. It is meant
+// as the last layer of a CompositeParkExtractor, where it geocodes the address the layers above
+// it found, and only if none of them found coordinates.
+type GeocodeParkExtractor struct {
+	address  fieldSelector
+	geocoder *services.GeocodingService
+}
+
+// NewGeocodeParkExtractor creates a geocoding layer. address optionally selects the address on
+// the page, for when no layer above finds one; without it the page's schema.org address is used.
+func NewGeocodeParkExtractor(address configHelper.SelectorConfig, geocoder *services.GeocodingService) (*GeocodeParkExtractor, error) {
+	if geocoder == nil {
+		return nil, fmt.Errorf("geocode extractor needs a geocoding service")
+	}
+
+	selector, err := compileSelector(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address selector: %w", err)
+	}
+
+	return &GeocodeParkExtractor{
+		address:  selector,
+		geocoder: geocoder,
+	}, nil
+}
+
+// ExtractParkData geocodes the address found on the page on its own
+func (g *GeocodeParkExtractor) ExtractParkData(ctx context.Context, e *colly.HTMLElement) (*models.Park, []string, error) {
+	return g.FillPark(ctx, e, models.Park{})
+}
+
+// FillPark geocodes merged's address, or else the one on the page, unless merged already has coordinates
+func (g *GeocodeParkExtractor) FillPark(ctx context.Context, e *colly.HTMLElement, merged models.Park) (*models.Park, []string, error) {
+	issues := &extractionIssues{}
+	park := &models.Park{}
+
+	if merged.Latitude != 0 || merged.Longitude != 0 {
+		return park, nil, nil
+	}
+
+	address := merged.Address
+	if address == "" {
+		address = g.address.first(e)
+	}
+	if address == "" {
+		if place, ok := ExtractSchemaOrgPlace(e); ok {
+			address = place.Address
+		}
+	}
+	if address == "" {
+		issues.warn("no address to geocode")
+		issues.missingField("coordinates")
+		return park, nil, issues.err()
+	}
+	park.Address = address
+
+	coords, err := g.geocoder.GeocodeAddress(ctx, address)
+	if err != nil {
+		issues.warn("geocoding %q failed: %v", address, err)
+		issues.missingField("coordinates")
+		return park, nil, issues.err()
+	}
+
+	park.Latitude = coords.Latitude
+	park.Longitude = coords.Longitude
+	return park, nil, nil
+}
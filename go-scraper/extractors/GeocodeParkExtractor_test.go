@@ -0,0 +1,179 @@
+package extractors
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"scraper/configHelper"
+	"scraper/models"
+	"scraper/services"
+	"strings"
+	"testing"
+)
+
+// recordingGeocoder returns a geocoder that answers every address with the same point and
+// records the addresses it was asked for
+func recordingGeocoder(addresses *[]string) *services.GeocodingService {
+	return services.NewGeocodingServiceWithClient("test-key", &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			path := strings.TrimSuffix(req.URL.EscapedPath()[strings.LastIndex(req.URL.EscapedPath(), "/")+1:], ".json")
+			address, _ := url.QueryUnescape(path)
+			*addresses = append(*addresses, address)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{"features":[{"center":[-88.5,42.5]}]}`)),
+				Request:    req,
+			}, nil
+		}),
+	})
+}
+
+func TestGeocodeLayerGeocodesMergedAddress(t *testing.T) {
+	var asked []string
+	extractor, err := NewGeocodeParkExtractor(configHelper.SelectorConfig{Selector: "p.address"}, recordingGeocoder(&asked))
+	if err != nil {
+		t.Fatalf("NewGeocodeParkExtractor: %v", err)
+	}
+	composite := NewCompositeParkExtractor(
+		ExtractorLayer{Name: "css", Extractor: &stubExtractor{
+			park: &models.Park{Name: "Moraine Hills State Park", Address: "1510 S River Rd, McHenry, IL 60051"},
+			err:  &ExtractionError{Missing: []string{"coordinates"}},
+		}},
+		ExtractorLayer{Name: "geocode", Extractor: extractor},
+	)
+
+	park, warnings, err := extractFixture(t, composite, "css_park.html")
+	if err != nil {
+		t.Fatalf("extraction failed: %v", err)
+	}
+
+	// The address the layer above found wins over the one on the page
+	if !reflect.DeepEqual(asked, []string{"1510 S River Rd, McHenry, IL 60051"}) {
+		t.Errorf("geocoded %q, want only the merged address", asked)
+	}
+	if park.Latitude != 42.5 || park.Longitude != -88.5 || park.FieldSources["coordinates"] != "geocode" {
+		t.Errorf("got park %+v, want the geocoded coordinates from the geocode layer", park)
+	}
+	if park.FieldSources["address"] != "css" {
+		t.Errorf("address came from %q, want css", park.FieldSources["address"])
+	}
+	if len(warnings) != 0 {
+		t.Errorf("got warnings %v, want none", warnings)
+	}
+}
+
+func TestGeocodeLayerSkipsParksWithCoordinates(t *testing.T) {
+	var asked []string
+	extractor, err := NewGeocodeParkExtractor(configHelper.SelectorConfig{}, recordingGeocoder(&asked))
+	if err != nil {
+		t.Fatalf("NewGeocodeParkExtractor: %v", err)
+	}
+	composite := NewCompositeParkExtractor(
+		ExtractorLayer{Name: "builtin", Extractor: &stubExtractor{park: &models.Park{
+			Name: "Turkey Run State Park", Address: "8121 E Park Rd, Marshall, IN 47859", Latitude: 39.8834, Longitude: -87.2017,
+		}}},
+		ExtractorLayer{Name: "geocode", Extractor: extractor},
+	)
+
+	park, _, err := extractFixture(t, composite, "css_park.html")
+	if err != nil {
+		t.Fatalf("extraction failed: %v", err)
+	}
+	if len(asked) != 0 {
+		t.Errorf("geocoded %q for a park that had coordinates", asked)
+	}
+	if park.FieldSources["coordinates"] != "builtin" {
+		t.Errorf("coordinates came from %q, want builtin", park.FieldSources["coordinates"])
+	}
+}
+
+func TestGeocodeLayerFindsAddressOnItsOwn(t *testing.T) {
+	tests := []struct {
+		name    string
+		address configHelper.SelectorConfig
+		page    string
+		want    string
+	}{
+		{"selector", configHelper.SelectorConfig{Selector: "p.address", Regex: `Address:\s*(.+)`}, "css_park.html", "N1765 County Road G, Campbellsport, WI 53010"},
+		{"schema.org", configHelper.SelectorConfig{}, "schemaorg_no_geo.html", "28478 W Brandenburg Rd, Ingleside, IL 60041"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var asked []string
+			extractor, err := NewGeocodeParkExtractor(test.address, recordingGeocoder(&asked))
+			if err != nil {
+				t.Fatalf("NewGeocodeParkExtractor: %v", err)
+			}
+
+			park, _, err := extractFixture(t, extractor, test.page)
+			if err != nil {
+				t.Fatalf("extraction failed: %v", err)
+			}
+			if !reflect.DeepEqual(asked, []string{test.want}) {
+				t.Errorf("geocoded %q, want %q", asked, test.want)
+			}
+			if park.Address != test.want || park.Latitude != 42.5 || park.Longitude != -88.5 {
+				t.Errorf("got park %+v, want the address and its coordinates", park)
+			}
+		})
+	}
+}
+
+func TestGeocodeLayerWithoutAddress(t *testing.T) {
+	var asked []string
+	extractor, err := NewGeocodeParkExtractor(configHelper.SelectorConfig{}, recordingGeocoder(&asked))
+	if err != nil {
+		t.Fatalf("NewGeocodeParkExtractor: %v", err)
+	}
+
+	_, _, err = extractFixture(t, extractor, "css_park.html")
+	var extractionErr *ExtractionError
+	if !errors.As(err, &extractionErr) || !reflect.DeepEqual(extractionErr.Missing, []string{"coordinates"}) {
+		t.Errorf("got error %v, want missing coordinates", err)
+	}
+	if len(asked) != 0 {
+		t.Errorf("geocoded %q without an address", asked)
+	}
+
+	if _, err := NewGeocodeParkExtractor(configHelper.SelectorConfig{}, nil); err == nil {
+		t.Error("NewGeocodeParkExtractor accepted a nil geocoder")
+	}
+}
+
+func TestFactoryLeavesGeocodingToGeocodeLayer(t *testing.T) {
+	factory := NewExtractorFactory(stubGeocoder(`{"features":[]}`), nil)
+
+	composite, err := factory.createConfiguredExtractor("IN", configHelper.ExtractorConfig{
+		Type:   "composite",
+		Layers: []configHelper.ExtractorConfig{{Type: "builtin"}, {Type: "schema-org"}, {Type: "geocode"}},
+	}, factory.geocodingService)
+	if err != nil {
+		t.Fatalf("createConfiguredExtractor: %v", err)
+	}
+
+	layers := composite.(*CompositeParkExtractor).layers
+	if names := []string{layers[0].Name, layers[1].Name, layers[2].Name}; !reflect.DeepEqual(names, []string{"builtin", "schema-org", "geocode"}) {
+		t.Errorf("got layers %v", names)
+	}
+	if layers[0].Extractor.(*INParkExtractor).geocoder != nil || layers[1].Extractor.(*SchemaOrgParkExtractor).geocoder != nil {
+		t.Error("layers above the geocode layer still geocode")
+	}
+	if layers[2].Extractor.(*GeocodeParkExtractor).geocoder == nil {
+		t.Error("the geocode layer has no geocoder")
+	}
+
+	// Without a geocode layer, layers keep geocoding themselves
+	composite, err = factory.createConfiguredExtractor("IN", configHelper.ExtractorConfig{
+		Type:   "composite",
+		Layers: []configHelper.ExtractorConfig{{Type: "builtin"}, {Type: "schema-org"}},
+	}, factory.geocodingService)
+	if err != nil {
+		t.Fatalf("createConfiguredExtractor: %v", err)
+	}
+	if composite.(*CompositeParkExtractor).layers[0].Extractor.(*INParkExtractor).geocoder == nil {
+		t.Error("the builtin layer lost its geocoder without a geocode layer")
+	}
+}
@@ -1,8 +1,8 @@
 package extractors
 
 import (
+	"context"
 	"scraper/models"
-	"strconv"
 	"strings"
 
 	"github.com/gocolly/colly"
@@ -11,15 +11,31 @@ import (
 type ILParkExtractor struct {
 }
 
-func (s *ILParkExtractor) ExtractParkData(e *colly.HTMLElement) *models.Park{
+func (s *ILParkExtractor) ExtractParkData(ctx context.Context, e *colly.HTMLElement) (*models.Park, []string, error){
+	issues := &extractionIssues{}
+
 	// Extract park information
 	parkName := e.ChildText("h1")
 	latitudeStr := e.ChildText("div.cmp-contentfragment__element--parkLatitude p.cmp-contentfragment__element-value")
 	longitudeStr := e.ChildText("div.cmp-contentfragment__element--parkLongitude p.cmp-contentfragment__element-value")
 
+	if parkName == "" {
+		issues.missingField("name")
+	}
+
 	// Convert lat/long strings to float32
-	latitude, err1 := strconv.ParseFloat(latitudeStr, 32)
-	longitude, err2 := strconv.ParseFloat(longitudeStr, 32)
+	latitude, errLat := parseCoordinate(latitudeStr)
+	if errLat != nil {
+		issues.coordinateField("latitude", latitudeStr)
+	}
+	longitude, errLon := parseCoordinate(longitudeStr)
+	if errLon != nil {
+		issues.coordinateField("longitude", longitudeStr)
+	}
+	if errLat != nil || errLon != nil {
+		latitude, longitude = 0, 0
+	}
+
 	activities := []models.ParkActivity{}
 
 	e.ForEach("ul.cmp-contentfragment__element-linkList li a", func(_ int, el *colly.HTMLElement) {
@@ -33,17 +49,22 @@ func (s *ILParkExtractor) ExtractParkData(e *colly.HTMLElement) *models.Park{
 		activities = append(activities, activity)
 
 	})
+	if len(activities) == 0 {
+		issues.warn("no activities found")
+	}
 
-	// Only return park if we have valid data
-	if parkName != "" && err1 == nil && err2 == nil {
-		return &models.Park{
-			Name:       parkName,
-			StateCode:  "IL", // Illinois - could be extracted from page if needed
-			Latitude:   float32(latitude),
-			Longitude:  float32(longitude),
-			Activities: activities,
-		}
+	park := &models.Park{
+		Name:       parkName,
+		StateCode:  "IL", // Illinois - could be extracted from page if needed
+		Latitude:   latitude,
+		Longitude:  longitude,
+		Activities: activities,
+	}
+
+	// Without valid data the park is only partial
+	if err := issues.err(); err != nil {
+		return park, nil, err
 	}
 
-	return nil
+	return park, issues.warnings, nil
 }
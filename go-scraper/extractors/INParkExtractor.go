@@ -1,7 +1,7 @@
 package extractors
 
 import (
-	"fmt"
+	"context"
 	"scraper/models"
 	"scraper/services"
 	"strings"
@@ -13,13 +13,17 @@ type INParkExtractor struct {
 	geocoder *services.GeocodingService
 }
 
+// NewINParkExtractor creates the Indiana extractor. geocoder may be nil, in which case parks
+// without schema.org geo come back without coordinates, e.g. for a geocode layer to fill in.
 func NewINParkExtractor(geocoder *services.GeocodingService) *INParkExtractor {
 	return &INParkExtractor{
 		geocoder: geocoder,
 	}
 }
 
-func (s *INParkExtractor) ExtractParkData(e *colly.HTMLElement) *models.Park{
+func (s *INParkExtractor) ExtractParkData(ctx context.Context, e *colly.HTMLElement) (*models.Park, []string, error){
+	issues := &extractionIssues{}
+
 	// Extract park information
 	parkName := e.ChildText("h1")
 
@@ -95,19 +99,32 @@ func (s *INParkExtractor) ExtractParkData(e *colly.HTMLElement) *models.Park{
 		fullAddress = ""
 	}
 
-	// Default coordinates (center of Indiana)
-	latitude := float32(41.0)
-	longitude := float32(-86.0)
+	// Coordinates are only set from schema.org markup or geocoding, never guessed
+	var latitude, longitude float32
+
+	// Coordinates declared with schema.org markup save a geocoding request
+	place, hasPlace := ExtractSchemaOrgPlace(e)
+	if hasPlace && fullAddress == "" {
+		fullAddress = place.Address
+	}
 
-	// Try to geocode the address if we found one
-	if fullAddress != "" && s.geocoder != nil {
-		coords, err := s.geocoder.GeocodeAddress(fullAddress)
+	if hasPlace && place.HasGeo {
+		latitude = place.Latitude
+		longitude = place.Longitude
+	} else if fullAddress == "" {
+		issues.warn("no address found")
+		issues.missingField("coordinates")
+	} else if s.geocoder == nil {
+		issues.missingField("coordinates")
+	} else {
+		// Try to geocode the address if we found one
+		coords, err := s.geocoder.GeocodeAddress(ctx, fullAddress)
 		if err != nil {
-			fmt.Printf("[GEOCODING ERROR] Failed to geocode address '%s': %v\n", fullAddress, err)
+			issues.warn("geocoding %q failed: %v", fullAddress, err)
+			issues.missingField("coordinates")
 		} else {
 			latitude = coords.Latitude
 			longitude = coords.Longitude
-			fmt.Printf("[GEOCODING SUCCESS] %s -> (%.6f, %.6f)\n", fullAddress, latitude, longitude)
 		}
 	}
 
@@ -127,17 +144,28 @@ func (s *INParkExtractor) ExtractParkData(e *colly.HTMLElement) *models.Park{
           }
       })
 
-	// Only return park if we have valid data
-	if parkName != ""  {
-		return &models.Park{
-			Name:       parkName,
-			StateCode:  "IN",
-			Address:    fullAddress,
-			Latitude:   latitude,
-			Longitude:  longitude,
-			Activities: activities,
-		}
+	park := &models.Park{
+		Name:       parkName,
+		StateCode:  "IN",
+		Address:    fullAddress,
+		Latitude:   latitude,
+		Longitude:  longitude,
+		Activities: activities,
+	}
+	if hasPlace {
+		place.fillMissing(park)
+	}
+
+	// Without valid data the park is only partial
+	if park.Name == "" {
+		issues.missingField("name")
+	}
+	if len(park.Activities) == 0 {
+		issues.warn("no activities found")
+	}
+	if err := issues.err(); err != nil {
+		return park, nil, err
 	}
 
-	return nil
+	return park, issues.warnings, nil
 }
\ No newline at end of file
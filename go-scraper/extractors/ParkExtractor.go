@@ -1,11 +1,96 @@
 package extractors
 
 import (
+	"context"
+	"fmt"
 	"scraper/models"
+	"sort"
+	"strings"
 
 	"github.com/gocolly/colly"
 )
 
 type ParkExtractor interface{
-	ExtractParkData(e *colly.HTMLElement) *models.Park
-}
\ No newline at end of file
+	// ExtractParkData builds a park from a page body. ctx bounds any extra lookups, such as geocoding.
+	// If fields a park needs are missing or can't be parsed, err is an *ExtractionError and park,
+	// if not nil, holds the fields that were found so a CompositeParkExtractor can still use them.
+	// Coordinates are left at zero unless both were found.
+	// warnings lists problems that didn't stop the park from being built, like a failed geocode.
+	ExtractParkData(ctx context.Context, e *colly.HTMLElement) (park *models.Park, warnings []string, err error)
+}
+
+// ExtractionError reports the required fields a page did not yield
+type ExtractionError struct {
+	Missing     []string          // Required fields not found on the page
+	Unparseable map[string]string // Required fields found but not understood, with their raw value
+	Warnings    []string          // Non-fatal problems noticed before giving up
+}
+
+func (e *ExtractionError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(e.Missing, ", "))
+	}
+
+	fields := make([]string, 0, len(e.Unparseable))
+	for field := range e.Unparseable {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		parts = append(parts, fmt.Sprintf("unparseable %s %q", field, e.Unparseable[field]))
+	}
+
+	message := "park extraction failed: " + strings.Join(parts, "; ")
+	if len(e.Warnings) > 0 {
+		message += " (warnings: " + strings.Join(e.Warnings, "; ") + ")"
+	}
+	return message
+}
+
+// extractionIssues collects the problems found while one page is extracted
+type extractionIssues struct {
+	missing     []string
+	unparseable map[string]string
+	warnings    []string
+}
+
+// missingField records a required field that was not found
+func (i *extractionIssues) missingField(field string) {
+	i.missing = append(i.missing, field)
+}
+
+// unparseableField records a required field whose value could not be parsed
+func (i *extractionIssues) unparseableField(field string, raw string) {
+	if i.unparseable == nil {
+		i.unparseable = make(map[string]string)
+	}
+	i.unparseable[field] = raw
+}
+
+// coordinateField reports a coordinate that parseCoordinate rejected as missing or unparseable
+func (i *extractionIssues) coordinateField(field string, raw string) {
+	if strings.TrimSpace(raw) == "" {
+		i.missingField(field)
+	} else {
+		i.unparseableField(field, raw)
+	}
+}
+
+// warn records a non-fatal problem
+func (i *extractionIssues) warn(format string, args ...any) {
+	i.warnings = append(i.warnings, fmt.Sprintf(format, args...))
+}
+
+// err returns an *ExtractionError if a required field was missing or unparseable, or nil
+func (i *extractionIssues) err() error {
+	if len(i.missing) == 0 && len(i.unparseable) == 0 {
+		return nil
+	}
+
+	return &ExtractionError{
+		Missing:     i.missing,
+		Unparseable: i.unparseable,
+		Warnings:    i.warnings,
+	}
+}
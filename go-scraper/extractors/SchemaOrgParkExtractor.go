@@ -0,0 +1,396 @@
+package extractors
+
+import (
+	"context"
+	"encoding/json"
+	"scraper/models"
+	"scraper/services"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+)
+
+// SchemaOrgPlace is what a page declares about itself with schema.org markup,
+// either as JSON-LD or as microdata
+type SchemaOrgPlace struct {
+	Name         string
+	Address      string
+	Telephone    string
+	OpeningHours []string
+	Amenities    []string // amenityFeature names, which park pages use for activities
+	Latitude     float32
+	Longitude    float32
+	HasGeo       bool // Whether Latitude and Longitude were declared
+}
+
+// SchemaOrgParkExtractor reads parks from schema.org markup. It works on any site that
+// publishes a Park (or other Place) in JSON-LD or microdata, without site specific selectors.
+type SchemaOrgParkExtractor struct {
+	stateCode string
+	geocoder  *services.GeocodingService
+}
+
+// NewSchemaOrgParkExtractor creates an extractor for parks in stateCode. geocoder may be nil,
+// in which case parks whose markup has no geo are dropped.
+func NewSchemaOrgParkExtractor(stateCode string, geocoder *services.GeocodingService) *SchemaOrgParkExtractor {
+	return &SchemaOrgParkExtractor{
+		stateCode: stateCode,
+		geocoder:  geocoder,
+	}
+}
+
+func (s *SchemaOrgParkExtractor) ExtractParkData(ctx context.Context, e *colly.HTMLElement) (*models.Park, []string, error) {
+	issues := &extractionIssues{}
+
+	place, ok := ExtractSchemaOrgPlace(e)
+	if !ok {
+		issues.warn("no schema.org Park or Place markup found")
+	}
+	if place.Name == "" {
+		issues.missingField("name")
+	}
+
+	park := &models.Park{
+		Name:       place.Name,
+		StateCode:  s.stateCode,
+		Activities: []models.ParkActivity{},
+	}
+	place.fillMissing(park)
+
+	if ok && !place.HasGeo {
+		switch {
+		case park.Address == "" || s.geocoder == nil:
+			issues.missingField("geo")
+		default:
+			coords, err := s.geocoder.GeocodeAddress(ctx, park.Address)
+			if err != nil {
+				issues.warn("geocoding %q failed: %v", park.Address, err)
+				issues.missingField("geo")
+			} else {
+				park.Latitude = coords.Latitude
+				park.Longitude = coords.Longitude
+			}
+		}
+	}
+
+	if err := issues.err(); err != nil {
+		return park, nil, err
+	}
+	if len(park.Activities) == 0 {
+		issues.warn("no activities found")
+	}
+
+	return park, issues.warnings, nil
+}
+
+// ExtractSchemaOrgPlace looks for a schema.org Park on the page, or failing that any Place
+// with a location. JSON-LD is preferred over microdata. ok is false if neither is found.
+func ExtractSchemaOrgPlace(e *colly.HTMLElement) (SchemaOrgPlace, bool) {
+	// Extractors get the <body>, but JSON-LD usually sits in the <head>
+	document := e.DOM.Closest("html")
+	if document.Length() == 0 {
+		document = e.DOM
+	}
+
+	if place, ok := placeFromJSONLD(document); ok {
+		return place, true
+	}
+	return placeFromMicrodata(document)
+}
+
+// fillMissing copies the place's fields into the fields park left empty. It is how other
+// extractors use schema.org markup as a fallback for what their selectors miss.
+func (p SchemaOrgPlace) fillMissing(park *models.Park) {
+	if park.Name == "" {
+		park.Name = p.Name
+	}
+	if park.Address == "" {
+		park.Address = p.Address
+	}
+	if park.Telephone == "" {
+		park.Telephone = p.Telephone
+	}
+	if len(park.OpeningHours) == 0 {
+		park.OpeningHours = p.OpeningHours
+	}
+	if len(park.Activities) == 0 {
+		for _, amenity := range p.Amenities {
+			park.Activities = append(park.Activities, models.ParkActivity{Name: amenity})
+		}
+	}
+	if p.HasGeo && park.Latitude == 0 && park.Longitude == 0 {
+		park.Latitude = p.Latitude
+		park.Longitude = p.Longitude
+	}
+}
+
+// placeTypes are the schema.org types accepted as a park, best first
+var placeTypes = []string{"Park", "StateOrProvincialPark", "Campground", "TouristAttraction", "LandmarksOrHistoricalBuildings", "Place"}
+
+// placeRank returns where a node's types fall in placeTypes, or -1 if it is not a place
+func placeRank(types []string) int {
+	for rank, placeType := range placeTypes {
+		for _, t := range types {
+			if strings.EqualFold(strings.TrimPrefix(t, "schema:"), placeType) {
+				return rank
+			}
+		}
+	}
+	return -1
+}
+
+// placeFromJSONLD reads the best ranked place out of the page's JSON-LD scripts
+func placeFromJSONLD(document *goquery.Selection) (SchemaOrgPlace, bool) {
+	var best map[string]any
+	bestRank := -1
+
+	document.Find(`script[type="application/ld+json"]`).Each(func(_ int, script *goquery.Selection) {
+		var data any
+		if err := json.Unmarshal([]byte(script.Text()), &data); err != nil {
+			return
+		}
+
+		for _, node := range jsonLDNodes(data) {
+			rank := placeRank(jsonLDStrings(node["@type"]))
+			if rank >= 0 && (best == nil || rank < bestRank) {
+				best, bestRank = node, rank
+			}
+		}
+	})
+
+	if best == nil {
+		return SchemaOrgPlace{}, false
+	}
+
+	place := SchemaOrgPlace{
+		Name:         firstString(jsonLDStrings(best["name"])),
+		Telephone:    firstString(jsonLDStrings(best["telephone"])),
+		OpeningHours: jsonLDStrings(best["openingHours"]),
+		Address:      jsonLDAddress(best["address"]),
+	}
+
+	for _, spec := range jsonLDObjects(best["openingHoursSpecification"]) {
+		days := jsonLDStrings(spec["dayOfWeek"])
+		for i := range days {
+			days[i] = days[i][strings.LastIndex(days[i], "/")+1:]
+		}
+		opens, closes := firstString(jsonLDStrings(spec["opens"])), firstString(jsonLDStrings(spec["closes"]))
+		if len(days) > 0 && opens != "" && closes != "" {
+			place.OpeningHours = append(place.OpeningHours, strings.Join(days, ",")+" "+opens+"-"+closes)
+		}
+	}
+
+	for _, feature := range jsonLDObjects(best["amenityFeature"]) {
+		if name := firstString(jsonLDStrings(feature["name"])); name != "" {
+			place.Amenities = append(place.Amenities, name)
+		}
+	}
+
+	for _, geo := range jsonLDObjects(best["geo"]) {
+		lat, errLat := parseCoordinate(firstString(jsonLDStrings(geo["latitude"])))
+		lon, errLon := parseCoordinate(firstString(jsonLDStrings(geo["longitude"])))
+		if errLat == nil && errLon == nil {
+			place.Latitude, place.Longitude, place.HasGeo = lat, lon, true
+			break
+		}
+	}
+
+	return place, true
+}
+
+// jsonLDNodes flattens a JSON-LD document, including arrays and @graph, into its objects
+func jsonLDNodes(value any) []map[string]any {
+	var nodes []map[string]any
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			nodes = append(nodes, jsonLDNodes(item)...)
+		}
+	case map[string]any:
+		nodes = append(nodes, v)
+		nodes = append(nodes, jsonLDNodes(v["@graph"])...)
+	}
+	return nodes
+}
+
+// jsonLDObjects returns value as a list of objects, since JSON-LD allows one or many
+func jsonLDObjects(value any) []map[string]any {
+	var objects []map[string]any
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			if object, ok := item.(map[string]any); ok {
+				objects = append(objects, object)
+			}
+		}
+	case map[string]any:
+		objects = append(objects, v)
+	}
+	return objects
+}
+
+// jsonLDStrings returns the text values of a JSON-LD property, which may be a string,
+// a number or a list of them
+func jsonLDStrings(value any) []string {
+	var values []string
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			values = append(values, jsonLDStrings(item)...)
+		}
+	case string:
+		if s := strings.Join(strings.Fields(v), " "); s != "" {
+			values = append(values, s)
+		}
+	case float64:
+		values = append(values, strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	return values
+}
+
+// jsonLDAddress formats an address given either as text or as a PostalAddress
+func jsonLDAddress(value any) string {
+	if text := firstString(jsonLDStrings(value)); text != "" {
+		return text
+	}
+
+	for _, address := range jsonLDObjects(value) {
+		formatted := formatAddress(
+			firstString(jsonLDStrings(address["streetAddress"])),
+			firstString(jsonLDStrings(address["addressLocality"])),
+			firstString(jsonLDStrings(address["addressRegion"])),
+			firstString(jsonLDStrings(address["postalCode"])),
+		)
+		if formatted != "" {
+			return formatted
+		}
+	}
+	return ""
+}
+
+// placeFromMicrodata reads the best ranked itemscope with a schema.org place type
+func placeFromMicrodata(document *goquery.Selection) (SchemaOrgPlace, bool) {
+	var best *goquery.Selection
+	bestRank := -1
+
+	document.Find(`[itemscope][itemtype*="schema.org"]`).Each(func(_ int, scope *goquery.Selection) {
+		var types []string
+		for _, itemType := range strings.Fields(scope.AttrOr("itemtype", "")) {
+			types = append(types, itemType[strings.LastIndex(itemType, "/")+1:])
+		}
+
+		rank := placeRank(types)
+		if rank >= 0 && (best == nil || rank < bestRank) {
+			best, bestRank = scope, rank
+		}
+	})
+
+	if best == nil {
+		return SchemaOrgPlace{}, false
+	}
+
+	place := SchemaOrgPlace{
+		Name:         firstString(microdataValues(best, "name")),
+		Telephone:    firstString(microdataValues(best, "telephone")),
+		OpeningHours: microdataValues(best, "openingHours"),
+	}
+
+	microdataProps(best, "address").EachWithBreak(func(_ int, address *goquery.Selection) bool {
+		if _, nested := address.Attr("itemscope"); nested {
+			place.Address = formatAddress(
+				firstString(microdataValues(address, "streetAddress")),
+				firstString(microdataValues(address, "addressLocality")),
+				firstString(microdataValues(address, "addressRegion")),
+				firstString(microdataValues(address, "postalCode")),
+			)
+		} else {
+			place.Address = microdataValue(address)
+		}
+		return place.Address == ""
+	})
+
+	microdataProps(best, "amenityFeature").Each(func(_ int, feature *goquery.Selection) {
+		if name := firstString(microdataValues(feature, "name")); name != "" {
+			place.Amenities = append(place.Amenities, name)
+		}
+	})
+
+	microdataProps(best, "geo").EachWithBreak(func(_ int, geo *goquery.Selection) bool {
+		lat, errLat := parseCoordinate(firstString(microdataValues(geo, "latitude")))
+		lon, errLon := parseCoordinate(firstString(microdataValues(geo, "longitude")))
+		if errLat == nil && errLon == nil {
+			place.Latitude, place.Longitude, place.HasGeo = lat, lon, true
+		}
+		return !place.HasGeo
+	})
+
+	return place, true
+}
+
+// microdataProps returns the elements carrying itemprop=prop that belong to scope itself,
+// leaving out those of nested itemscopes
+func microdataProps(scope *goquery.Selection, prop string) *goquery.Selection {
+	return scope.Find("[itemprop]").FilterFunction(func(_ int, el *goquery.Selection) bool {
+		if !containsField(el.AttrOr("itemprop", ""), prop) {
+			return false
+		}
+		return el.Parent().Closest("[itemscope]").IsSelection(scope)
+	})
+}
+
+// microdataValues returns the non-empty values of prop within scope
+func microdataValues(scope *goquery.Selection, prop string) []string {
+	var values []string
+	microdataProps(scope, prop).Each(func(_ int, el *goquery.Selection) {
+		if value := microdataValue(el); value != "" {
+			values = append(values, value)
+		}
+	})
+	return values
+}
+
+// microdataValue reads an itemprop's value from its content attribute, or else its text
+func microdataValue(el *goquery.Selection) string {
+	value, ok := el.Attr("content")
+	if !ok {
+		value = el.Text()
+	}
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// containsField reports whether the space separated list contains field
+func containsField(list string, field string) bool {
+	for _, f := range strings.Fields(list) {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// formatAddress joins PostalAddress parts as "street, city, ST 12345", skipping missing parts
+func formatAddress(street, locality, region, postalCode string) string {
+	regionPostal := strings.TrimSpace(region + " " + postalCode)
+
+	var parts []string
+	for _, part := range []string{street, locality, regionPostal} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func parseCoordinate(value string) (float32, error) {
+	coordinate, err := strconv.ParseFloat(strings.TrimSpace(value), 32)
+	return float32(coordinate), err
+}
+
+func firstString(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,111 @@
+package extractors
+
+import (
+	"errors"
+	"reflect"
+	"scraper/models"
+	"testing"
+)
+
+func TestSchemaOrgParkExtractorReadsJSONLD(t *testing.T) {
+	park, warnings, err := extractFixture(t, NewSchemaOrgParkExtractor("IL", nil), "schemaorg_jsonld.html")
+	if err != nil {
+		t.Fatalf("extraction failed: %v", err)
+	}
+
+	// The Park in the @graph wins over the WebPage and the lower ranked TouristAttraction,
+	// and the script that isn't valid JSON is ignored
+	want := &models.Park{
+		Name:         "Starved Rock State Park",
+		StateCode:    "IL",
+		Address:      "2678 E 875th Rd, Oglesby, IL 61348",
+		Telephone:    "815-667-4726",
+		OpeningHours: []string{"Monday,Tuesday 07:00-21:00"},
+		Latitude:     41.3189,
+		Longitude:    -88.9909,
+		Activities:   []models.ParkActivity{{Name: "Hiking"}, {Name: "Ice Climbing"}},
+	}
+	if !reflect.DeepEqual(park, want) {
+		t.Errorf("got park %+v, want %+v", park, want)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("got warnings %v, want none", warnings)
+	}
+}
+
+func TestSchemaOrgParkExtractorReadsMicrodata(t *testing.T) {
+	park, warnings, err := extractFixture(t, NewSchemaOrgParkExtractor("IN", nil), "schemaorg_microdata.html")
+	if err != nil {
+		t.Fatalf("extraction failed: %v", err)
+	}
+
+	// The properties of the nested Event are not the park's
+	want := &models.Park{
+		Name:         "Brown County State Park",
+		StateCode:    "IN",
+		Address:      "1405 State Road 46 W, Nashville, IN 47448",
+		Telephone:    "812-988-6406",
+		OpeningHours: []string{"Mo-Su 07:00-23:00"},
+		Latitude:     39.1789,
+		Longitude:    -86.2381,
+		Activities:   []models.ParkActivity{{Name: "Camping"}, {Name: "Horseback Riding"}},
+	}
+	if !reflect.DeepEqual(park, want) {
+		t.Errorf("got park %+v, want %+v", park, want)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("got warnings %v, want none", warnings)
+	}
+}
+
+func TestSchemaOrgParkExtractorGeocodesAddressWithoutGeo(t *testing.T) {
+	geocoder := stubGeocoder(`{"features":[{"center":[-88.1865,42.3519]}]}`)
+	park, warnings, err := extractFixture(t, NewSchemaOrgParkExtractor("IL", geocoder), "schemaorg_no_geo.html")
+	if err != nil {
+		t.Fatalf("extraction failed: %v", err)
+	}
+	if park.Latitude != 42.3519 || park.Longitude != -88.1865 {
+		t.Errorf("got coordinates (%v, %v), want the geocoded (42.3519, -88.1865)", park.Latitude, park.Longitude)
+	}
+	if !reflect.DeepEqual(warnings, []string{"no activities found"}) {
+		t.Errorf("got warnings %v, want only the missing activities", warnings)
+	}
+}
+
+func TestSchemaOrgParkExtractorRejectsPagesWithoutGeo(t *testing.T) {
+	tests := []struct {
+		name      string
+		extractor *SchemaOrgParkExtractor
+		warnings  int
+	}{
+		{"no geocoder", NewSchemaOrgParkExtractor("IL", nil), 0},
+		{"failed geocode", NewSchemaOrgParkExtractor("IL", stubGeocoder(`{"features":[]}`)), 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := extractFixture(t, test.extractor, "schemaorg_no_geo.html")
+			var extractionErr *ExtractionError
+			if !errors.As(err, &extractionErr) {
+				t.Fatalf("got error %v, want an *ExtractionError", err)
+			}
+			if !reflect.DeepEqual(extractionErr.Missing, []string{"geo"}) {
+				t.Errorf("got missing fields %v, want [geo]", extractionErr.Missing)
+			}
+			if len(extractionErr.Warnings) != test.warnings {
+				t.Errorf("got warnings %v, want %d", extractionErr.Warnings, test.warnings)
+			}
+		})
+	}
+}
+
+func TestSchemaOrgParkExtractorRejectsPagesWithoutMarkup(t *testing.T) {
+	_, _, err := extractFixture(t, NewSchemaOrgParkExtractor("WI", nil), "css_park.html")
+	var extractionErr *ExtractionError
+	if !errors.As(err, &extractionErr) {
+		t.Fatalf("got error %v, want an *ExtractionError", err)
+	}
+	if !reflect.DeepEqual(extractionErr.Missing, []string{"name"}) {
+		t.Errorf("got missing fields %v, want [name]", extractionErr.Missing)
+	}
+}
@@ -1,26 +1,104 @@
 package extractors
 
-import "scraper/services"
+import (
+	"fmt"
+	"log"
+	"scraper/configHelper"
+	"scraper/services"
+	"sort"
+)
 
 // ExtractorFactory creates extractors based on state code
 type ExtractorFactory struct{
 	geocodingService *services.GeocodingService
+	urlConfig        *configHelper.URLConfig
 }
 
-// NewExtractorFactory creates a new ExtractorFactory
-func NewExtractorFactory(geocodingService *services.GeocodingService) *ExtractorFactory {
+// NewExtractorFactory creates a new ExtractorFactory. States with an "extractor" entry in
+// urlConfig get the extractor it describes instead of their Go extractor; urlConfig may be
+// nil to only use the Go extractors.
+func NewExtractorFactory(geocodingService *services.GeocodingService, urlConfig *configHelper.URLConfig) *ExtractorFactory {
 	return &ExtractorFactory{
 		geocodingService: geocodingService,
+		urlConfig:        urlConfig,
 	}
 }
 
 // CreateExtractor returns the appropriate extractor for a state code
 func (f *ExtractorFactory) CreateExtractor(stateCode string) ParkExtractor {
+	if f.urlConfig != nil {
+		if config, ok := f.urlConfig.GetExtractorByState(stateCode); ok {
+			extractor, err := f.createConfiguredExtractor(stateCode, config, f.geocodingService)
+			if err != nil {
+				log.Printf("Invalid extractor config: %v", err)
+				return nil
+			}
+			return extractor
+		}
+	}
+
+	return f.createBuiltinExtractor(stateCode, f.geocodingService)
+}
+
+// createConfiguredExtractor builds the extractor an "extractor" entry in urls.json describes.
+// geocoder is what the extractor geocodes addresses with, nil to leave that to a geocode layer.
+func (f *ExtractorFactory) createConfiguredExtractor(stateCode string, config configHelper.ExtractorConfig, geocoder *services.GeocodingService) (ParkExtractor, error) {
+	switch config.Type {
+	case "schema-org":
+		return NewSchemaOrgParkExtractor(stateCode, geocoder), nil
+	case "geocode":
+		return NewGeocodeParkExtractor(config.Address, f.geocodingService)
+	case "builtin":
+		extractor := f.createBuiltinExtractor(stateCode, geocoder)
+		if extractor == nil {
+			return nil, fmt.Errorf("there is no builtin extractor for %s", stateCode)
+		}
+		return extractor, nil
+	case "composite":
+		if len(config.Layers) == 0 {
+			return nil, fmt.Errorf("composite extractor for %s has no layers", stateCode)
+		}
+
+		// With a geocode layer the other layers don't geocode, so an address is only geocoded once
+		layerGeocoder := geocoder
+		for _, layerConfig := range config.Layers {
+			if layerConfig.Type == "geocode" {
+				layerGeocoder = nil
+			}
+		}
+
+		layers := make([]ExtractorLayer, 0, len(config.Layers))
+		used := make(map[string]bool)
+		for i, layerConfig := range config.Layers {
+			extractor, err := f.createConfiguredExtractor(stateCode, layerConfig, layerGeocoder)
+			if err != nil {
+				return nil, fmt.Errorf("layer %d: %w", i+1, err)
+			}
+
+			name := layerConfig.Type
+			if name == "" {
+				name = "css"
+			}
+			if used[name] {
+				name = fmt.Sprintf("%s#%d", name, i+1)
+			}
+			used[name] = true
+
+			layers = append(layers, ExtractorLayer{Name: name, Extractor: extractor})
+		}
+		return NewCompositeParkExtractor(layers...), nil
+	default:
+		return NewCSSParkExtractor(stateCode, config, geocoder)
+	}
+}
+
+// createBuiltinExtractor returns the Go extractor written for a state, or nil if there is none
+func (f *ExtractorFactory) createBuiltinExtractor(stateCode string, geocoder *services.GeocodingService) ParkExtractor {
 	switch stateCode {
 	case "IL":
 		return &ILParkExtractor{}
 	case "IN":
-		return NewINParkExtractor(f.geocodingService)
+		return NewINParkExtractor(geocoder)
 	default:
 		return nil
 	}
@@ -28,7 +106,16 @@ func (f *ExtractorFactory) CreateExtractor(stateCode string) ParkExtractor {
 
 // GetSupportedStates returns a list of all supported state codes
 func (f *ExtractorFactory) GetSupportedStates() []string {
-	return []string{"IL", "IN"}
+	states := []string{"IL", "IN"}
+	if f.urlConfig != nil {
+		for _, stateCode := range f.urlConfig.GetAllStates() {
+			if _, ok := f.urlConfig.GetExtractorByState(stateCode); ok && stateCode != "IL" && stateCode != "IN" {
+				states = append(states, stateCode)
+			}
+		}
+	}
+	sort.Strings(states)
+	return states
 }
 
 // IsStateSupported checks if a state code is supported
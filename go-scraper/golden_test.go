@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"scraper/configHelper"
+	"scraper/events"
+	"scraper/extractors"
+	"scraper/journal"
+	"scraper/models"
+	"scraper/scrapers"
+	"scraper/services"
+	"scraper/transport"
+	"sort"
+	"testing"
+	"time"
+)
+
+// The fixtures under testdata/fixtures hold the listing, park pages and geocoding responses of the
+// states below. Re-record them with: go run . -record testdata/fixtures -states IL,IN -no-api
+// and then rewrite the expected parks with: go test -run TestGolden -update
+var updateGolden = flag.Bool("update", false, "rewrite testdata/golden from the current extractors")
+
+const (
+	fixtureDir = "testdata/fixtures"
+	goldenDir  = "testdata/golden"
+)
+
+// goldenState is what scraping one state from the fixtures is expected to give
+type goldenState struct {
+	Parks  []goldenPark    `json:"parks"`
+	Failed []goldenFailure `json:"failed"`
+}
+
+type goldenPark struct {
+	URL      string      `json:"url"`
+	Park     models.Park `json:"park"`
+	Warnings []string    `json:"warnings,omitempty"`
+}
+
+type goldenFailure struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+func TestGoldenStates(t *testing.T) {
+	for _, stateCode := range []string{"IL", "IN"} {
+		t.Run(stateCode, func(t *testing.T) {
+			got := scrapeFixtures(t, stateCode)
+
+			data, err := json.MarshalIndent(got, "", "  ")
+			if err != nil {
+				t.Fatalf("failed to encode parks: %v", err)
+			}
+			data = append(data, '\n')
+
+			path := filepath.Join(goldenDir, stateCode+".json")
+			if *updateGolden {
+				if err := os.MkdirAll(goldenDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, data, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read golden file, create it with -update: %v", err)
+			}
+			if !bytes.Equal(data, want) {
+				t.Errorf("parks scraped from the %s fixtures differ from %s (rewrite it with -update if the change is intended)\ngot:\n%s", stateCode, path, data)
+			}
+		})
+	}
+}
+
+// scrapeFixtures scrapes stateCode the way main does, with config/urls.json, the gatherer
+// registry, the extractor factory and the geocoder, but answered from the recorded fixtures
+func scrapeFixtures(t *testing.T, stateCode string) goldenState {
+	t.Helper()
+
+	urlConfig, err := configHelper.LoadURLConfig("config/urls.json")
+	if err != nil {
+		t.Fatalf("failed to load URL config: %v", err)
+	}
+
+	replay := transport.NewReplayTransport(fixtureDir)
+	client := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: transport.NewRobotsTransport(scrapers.UserAgent, nil, replay),
+	}
+	// The key is redacted in the fixtures, any non-empty one matches them
+	geocoder := services.NewGeocodingServiceWithClient("test-key", &http.Client{Transport: replay})
+
+	runJournal, err := journal.Create(t.TempDir(), journal.NewRunID())
+	if err != nil {
+		t.Fatalf("failed to create run journal: %v", err)
+	}
+	defer runJournal.Close()
+
+	publisher := events.NewParkEventPublisher(context.Background())
+	defer publisher.Close()
+
+	run := &scrapeRun{
+		urlConfig:          urlConfig,
+		client:             client,
+		extractors:         extractors.NewExtractorFactory(geocoder, urlConfig),
+		gatherers:          scrapers.NewGathererRegistry(),
+		publisher:          publisher,
+		journal:            runJournal,
+		defaultConcurrency: 2,
+	}
+	result := run.scrapeState(context.Background(), stateCode)
+	if result.Err != nil {
+		t.Fatalf("scraping %s from fixtures failed: %v", stateCode, result.Err)
+	}
+
+	got := goldenState{Parks: []goldenPark{}, Failed: []goldenFailure{}}
+	for _, scraped := range result.Scrape.Succeeded {
+		got.Parks = append(got.Parks, goldenPark{URL: scraped.URL, Park: scraped.Park, Warnings: scraped.Warnings})
+	}
+	for _, failure := range result.Scrape.Failed {
+		got.Failed = append(got.Failed, goldenFailure{URL: failure.URL, Error: failure.Err.Error()})
+	}
+	sort.Slice(got.Parks, func(i, j int) bool { return got.Parks[i].URL < got.Parks[j].URL })
+	sort.Slice(got.Failed, func(i, j int) bool { return got.Failed[i].URL < got.Failed[j].URL })
+	return got
+}
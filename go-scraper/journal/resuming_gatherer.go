@@ -0,0 +1,91 @@
+package journal
+
+import (
+	"context"
+	"fmt"
+	"scraper/scrapers"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ResumingGatherer wraps a ParkUrlGatherer with a RunJournal. The first time a state is
+// gathered its URLs are recorded; on a resumed run the recorded URLs are reused instead of
+// gathering again. Either way, URLs the journal already has as completed are left out.
+type ResumingGatherer struct {
+	journal   *RunJournal
+	stateCode string
+	next      scrapers.ParkUrlGatherer
+}
+
+// NewResumingGatherer creates a ResumingGatherer for stateCode around next
+func NewResumingGatherer(journal *RunJournal, stateCode string, next scrapers.ParkUrlGatherer) *ResumingGatherer {
+	return &ResumingGatherer{
+		journal:   journal,
+		stateCode: stateCode,
+		next:      next,
+	}
+}
+
+// GatherUrls returns the park URLs of the state that still need to be scraped
+func (g *ResumingGatherer) GatherUrls(ctx context.Context, mainPageUrl string) ([]string, error) {
+	urls, ok := g.journal.GatheredURLs(g.stateCode)
+	if ok {
+		fmt.Printf("[JOURNAL] Reusing %d %s URLs gathered earlier in run %s\n", len(urls), g.stateCode, g.journal.RunID())
+	} else {
+		var err error
+		urls, err = g.next.GatherUrls(ctx, mainPageUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := g.journal.RecordGathered(g.stateCode, urls); err != nil {
+			return nil, err
+		}
+	}
+
+	pending := make([]string, 0, len(urls))
+	for _, url := range urls {
+		if !g.journal.IsCompleted(g.stateCode, url) {
+			pending = append(pending, url)
+		}
+	}
+
+	if done := len(urls) - len(pending); done > 0 {
+		warnings := g.journal.Warnings(g.stateCode)
+		fmt.Printf("[JOURNAL] Skipping %d %s parks already completed (%d with warnings), %d left (%d failed last time)\n",
+			done, g.stateCode, len(warnings), len(pending), len(g.journal.FailedURLs(g.stateCode)))
+
+		// Skipped parks won't show up in this run's summary, so their warnings are listed here
+		warned := make([]string, 0, len(warnings))
+		for url := range warnings {
+			warned = append(warned, url)
+		}
+		sort.Strings(warned)
+		for _, url := range warned {
+			fmt.Printf("[JOURNAL]   warnings: %s: %s\n", url, strings.Join(warnings[url], "; "))
+		}
+	}
+
+	return pending, nil
+}
+
+// PagesWalked passes through the listing pages the wrapped gatherer fetched. It is 0 when the
+// URLs were reused from the journal.
+func (g *ResumingGatherer) PagesWalked() int {
+	walker, ok := g.next.(scrapers.PageWalker)
+	if !ok {
+		return 0
+	}
+	return walker.PagesWalked()
+}
+
+// LastModified passes through the lastmod of the wrapped gatherer, if it reports one.
+// URLs reused from the journal have none.
+func (g *ResumingGatherer) LastModified(pageURL string) (time.Time, bool) {
+	reporter, ok := g.next.(scrapers.LastModifiedReporter)
+	if !ok {
+		return time.Time{}, false
+	}
+	return reporter.LastModified(pageURL)
+}
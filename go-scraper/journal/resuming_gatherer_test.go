@@ -0,0 +1,58 @@
+package journal
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// fakeGatherer returns urls and counts how often it was asked
+type fakeGatherer struct {
+	urls  []string
+	calls int
+}
+
+func (g *fakeGatherer) GatherUrls(ctx context.Context, mainPageUrl string) ([]string, error) {
+	g.calls++
+	return g.urls, nil
+}
+
+func TestResumingGathererRecordsThenReusesURLs(t *testing.T) {
+	dir := t.TempDir()
+	gathered := []string{starvedRock, matthiessen, kankakee}
+
+	j := mustCreate(t, dir, "run-1")
+	first := &fakeGatherer{urls: gathered}
+	urls, err := NewResumingGatherer(j, "IL", first).GatherUrls(context.Background(), "https://dnr.illinois.gov/parks")
+	if err != nil {
+		t.Fatalf("GatherUrls: %v", err)
+	}
+	if !reflect.DeepEqual(urls, gathered) || first.calls != 1 {
+		t.Errorf("got %v from %d calls, want every URL from one call", urls, first.calls)
+	}
+	completed(t, j, "IL", matthiessen)
+	j.Close()
+
+	// The resumed run doesn't gather again and leaves out the completed park
+	resumed := mustResume(t, dir, "run-1")
+	second := &fakeGatherer{urls: []string{"https://dnr.illinois.gov/parks/park.new.html"}}
+	urls, err = NewResumingGatherer(resumed, "IL", second).GatherUrls(context.Background(), "https://dnr.illinois.gov/parks")
+	if err != nil {
+		t.Fatalf("GatherUrls: %v", err)
+	}
+	if want := []string{starvedRock, kankakee}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("got %v, want %v", urls, want)
+	}
+	if second.calls != 0 {
+		t.Errorf("the gatherer was asked %d times, want the journaled URLs reused", second.calls)
+	}
+
+	// A state the earlier run never reached is gathered as usual
+	urls, err = NewResumingGatherer(resumed, "IN", second).GatherUrls(context.Background(), "https://www.in.gov/dnr")
+	if err != nil {
+		t.Fatalf("GatherUrls: %v", err)
+	}
+	if !reflect.DeepEqual(urls, second.urls) || second.calls != 1 {
+		t.Errorf("got %v from %d calls, want the gatherer's URLs", urls, second.calls)
+	}
+}
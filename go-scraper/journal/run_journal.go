@@ -0,0 +1,271 @@
+package journal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"scraper/events"
+	"sync"
+	"time"
+)
+
+// Entry types written to the journal file
+const (
+	entryGathered  = "gathered"
+	entryCompleted = "completed"
+	entryFailed    = "failed"
+)
+
+// entry is a single line of the journal file
+type entry struct {
+	Type      string    `json:"type"`
+	StateCode string    `json:"stateCode"`
+	URL       string    `json:"url,omitempty"`
+	URLs      []string  `json:"urls,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Warnings  []string  `json:"warnings,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// stateProgress is what the journal knows about one state
+type stateProgress struct {
+	gathered  []string
+	completed map[string]bool
+	failed    map[string]string   // URL -> last error
+	warnings  map[string][]string // URL -> warnings of the completed park
+}
+
+// RunJournal is an append-only, on-disk record of a scrape run. It records the URLs gathered
+// for each state, the parks delivered to subscribers and the parks that failed, so an
+// interrupted run can be resumed.
+//
+// RunJournal is a ParkEventSubscriber: a URL only counts as completed once every other subscriber
+// has handled its event. Subscribe it with SubscribeFinal.
+type RunJournal struct {
+	runID string
+	path  string
+
+	mu     sync.Mutex
+	file   *os.File
+	states map[string]*stateProgress
+}
+
+// NewRunID returns an ID for a new run based on the current time
+func NewRunID() string {
+	return time.Now().Format("20060102-150405")
+}
+
+// Create starts a new journal for runID under dir
+func Create(dir string, runID string) (*RunJournal, error) {
+	path := journalPath(dir, runID)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("journal for run %s already exists at %s", runID, path)
+	}
+
+	return open(path, runID)
+}
+
+// Resume reopens the journal of an earlier run under dir and replays what it recorded
+func Resume(dir string, runID string) (*RunJournal, error) {
+	path := journalPath(dir, runID)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("no journal found for run %s: %w", runID, err)
+	}
+
+	return open(path, runID)
+}
+
+// journalPath returns where the journal of runID is stored
+func journalPath(dir string, runID string) string {
+	return filepath.Join(dir, runID, "journal.jsonl")
+}
+
+// open opens the journal file for appending after loading any entries it already holds
+func open(path string, runID string) (*RunJournal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	j := &RunJournal{
+		runID:  runID,
+		path:   path,
+		states: make(map[string]*stateProgress),
+	}
+
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	j.file = file
+
+	return j, nil
+}
+
+// load replays the journal file into memory. A truncated last line, left behind by a
+// crash mid-write, is ignored.
+func (j *RunJournal) load() error {
+	file, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			log.Printf("[JOURNAL] Ignoring unreadable journal line: %v", err)
+			continue
+		}
+		j.apply(e)
+	}
+
+	return scanner.Err()
+}
+
+// apply updates the in-memory progress with e. Callers must hold j.mu (or own j exclusively).
+func (j *RunJournal) apply(e entry) {
+	progress, ok := j.states[e.StateCode]
+	if !ok {
+		progress = &stateProgress{
+			completed: make(map[string]bool),
+			failed:    make(map[string]string),
+			warnings:  make(map[string][]string),
+		}
+		j.states[e.StateCode] = progress
+	}
+
+	switch e.Type {
+	case entryGathered:
+		progress.gathered = e.URLs
+	case entryCompleted:
+		progress.completed[e.URL] = true
+		delete(progress.failed, e.URL)
+		if len(e.Warnings) > 0 {
+			progress.warnings[e.URL] = e.Warnings
+		}
+	case entryFailed:
+		if !progress.completed[e.URL] {
+			progress.failed[e.URL] = e.Error
+		}
+	}
+}
+
+// append writes e to the journal file and applies it
+func (j *RunJournal) append(e entry) error {
+	e.Timestamp = time.Now()
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal entry: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	j.apply(e)
+
+	return nil
+}
+
+// RunID returns the ID to pass to -resume to continue this run
+func (j *RunJournal) RunID() string {
+	return j.runID
+}
+
+// RecordGathered records the park URLs gathered for a state
+func (j *RunJournal) RecordGathered(stateCode string, urls []string) error {
+	return j.append(entry{Type: entryGathered, StateCode: stateCode, URLs: urls})
+}
+
+// RecordFailed records a park URL that could not be scraped
+func (j *RunJournal) RecordFailed(stateCode string, url string, err error) error {
+	return j.append(entry{Type: entryFailed, StateCode: stateCode, URL: url, Error: err.Error()})
+}
+
+// OnParkScraped records the event's URL as completed
+func (j *RunJournal) OnParkScraped(ctx context.Context, event events.ParkScrapedEvent) error {
+	if event.URL == "" {
+		log.Printf("[JOURNAL] Event for %s has no URL, it can't be journaled", event.StateCode)
+		return nil
+	}
+
+	return j.append(entry{Type: entryCompleted, StateCode: event.StateCode, URL: event.URL, Warnings: event.Warnings})
+}
+
+// GatheredURLs returns the URLs recorded for a state. ok is false if the state was never gathered.
+func (j *RunJournal) GatheredURLs(stateCode string) ([]string, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	progress, ok := j.states[stateCode]
+	if !ok || progress.gathered == nil {
+		return nil, false
+	}
+	return append([]string(nil), progress.gathered...), true
+}
+
+// IsCompleted reports whether the park at url was delivered to subscribers
+func (j *RunJournal) IsCompleted(stateCode string, url string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	progress, ok := j.states[stateCode]
+	return ok && progress.completed[url]
+}
+
+// FailedURLs returns the URLs of a state that failed and have not completed since, with their last error
+func (j *RunJournal) FailedURLs(stateCode string) map[string]string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	failed := make(map[string]string)
+	if progress, ok := j.states[stateCode]; ok {
+		for url, reason := range progress.failed {
+			failed[url] = reason
+		}
+	}
+	return failed
+}
+
+// Warnings returns the completed URLs of a state whose extraction had warnings, with those warnings
+func (j *RunJournal) Warnings(stateCode string) map[string][]string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	warnings := make(map[string][]string)
+	if progress, ok := j.states[stateCode]; ok {
+		for url, w := range progress.warnings {
+			warnings[url] = w
+		}
+	}
+	return warnings
+}
+
+// Close flushes and closes the journal file
+func (j *RunJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.file.Sync(); err != nil {
+		j.file.Close()
+		return err
+	}
+	return j.file.Close()
+}
@@ -0,0 +1,142 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"scraper/events"
+	"testing"
+)
+
+const (
+	starvedRock = "https://dnr.illinois.gov/parks/park.starved-rock.html"
+	matthiessen = "https://dnr.illinois.gov/parks/park.matthiessen.html"
+	kankakee    = "https://dnr.illinois.gov/parks/park.kankakee-river.html"
+)
+
+func mustCreate(t *testing.T, dir string, runID string) *RunJournal {
+	t.Helper()
+
+	j, err := Create(dir, runID)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return j
+}
+
+func mustResume(t *testing.T, dir string, runID string) *RunJournal {
+	t.Helper()
+
+	j, err := Resume(dir, runID)
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	t.Cleanup(func() { j.Close() })
+	return j
+}
+
+func completed(t *testing.T, j *RunJournal, stateCode string, url string, warnings ...string) {
+	t.Helper()
+
+	event := events.ParkScrapedEvent{StateCode: stateCode, URL: url, Warnings: warnings}
+	if err := j.OnParkScraped(context.Background(), event); err != nil {
+		t.Fatalf("OnParkScraped: %v", err)
+	}
+}
+
+func TestRunJournalResumesWhatWasRecorded(t *testing.T) {
+	dir := t.TempDir()
+	j := mustCreate(t, dir, "run-1")
+
+	gathered := []string{starvedRock, matthiessen, kankakee}
+	if err := j.RecordGathered("IL", gathered); err != nil {
+		t.Fatalf("RecordGathered: %v", err)
+	}
+	completed(t, j, "IL", starvedRock, "no phone number")
+	if err := j.RecordFailed("IL", matthiessen, errors.New("timeout")); err != nil {
+		t.Fatalf("RecordFailed: %v", err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	resumed := mustResume(t, dir, "run-1")
+	if urls, ok := resumed.GatheredURLs("IL"); !ok || !reflect.DeepEqual(urls, gathered) {
+		t.Errorf("GatheredURLs = %v, %v, want %v", urls, ok, gathered)
+	}
+	if _, ok := resumed.GatheredURLs("IN"); ok {
+		t.Error("GatheredURLs reports a state that was never gathered")
+	}
+	for url, want := range map[string]bool{starvedRock: true, matthiessen: false, kankakee: false} {
+		if got := resumed.IsCompleted("IL", url); got != want {
+			t.Errorf("IsCompleted(%s) = %v, want %v", url, got, want)
+		}
+	}
+	if failed := resumed.FailedURLs("IL"); !reflect.DeepEqual(failed, map[string]string{matthiessen: "timeout"}) {
+		t.Errorf("FailedURLs = %v, want matthiessen's timeout", failed)
+	}
+	if warnings := resumed.Warnings("IL"); !reflect.DeepEqual(warnings, map[string][]string{starvedRock: {"no phone number"}}) {
+		t.Errorf("Warnings = %v, want starved rock's", warnings)
+	}
+
+	// What the resumed run records is appended to the same journal
+	completed(t, resumed, "IL", matthiessen)
+	if failed := resumed.FailedURLs("IL"); len(failed) != 0 {
+		t.Errorf("FailedURLs = %v after the park completed, want none", failed)
+	}
+	if err := resumed.RecordFailed("IL", starvedRock, errors.New("late failure")); err != nil {
+		t.Fatalf("RecordFailed: %v", err)
+	}
+	if failed := resumed.FailedURLs("IL"); len(failed) != 0 {
+		t.Errorf("FailedURLs = %v, want a completed park to stay completed", failed)
+	}
+}
+
+func TestRunJournalCreateAndResumeErrors(t *testing.T) {
+	dir := t.TempDir()
+	j := mustCreate(t, dir, "run-1")
+	defer j.Close()
+
+	if _, err := Create(dir, "run-1"); err == nil {
+		t.Error("Create of an existing run succeeded, want an error")
+	}
+	if _, err := Resume(dir, "run-2"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Resume of an unknown run = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestRunJournalIgnoresTruncatedLastLine(t *testing.T) {
+	dir := t.TempDir()
+	j := mustCreate(t, dir, "run-1")
+	completed(t, j, "IL", starvedRock)
+	j.Close()
+
+	// As if the run crashed while writing its next entry
+	file, err := os.OpenFile(journalPath(dir, "run-1"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	file.WriteString(`{"type":"completed","stateCode":"IL","url":"` + matthiessen)
+	file.Close()
+
+	resumed := mustResume(t, dir, "run-1")
+	if !resumed.IsCompleted("IL", starvedRock) || resumed.IsCompleted("IL", matthiessen) {
+		t.Error("want only the entry written in full to be replayed")
+	}
+}
+
+func TestRunJournalSkipsEventsWithoutURL(t *testing.T) {
+	dir := t.TempDir()
+	j := mustCreate(t, dir, "run-1")
+	completed(t, j, "IL", "")
+	j.Close()
+
+	data, err := os.ReadFile(journalPath(dir, "run-1"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if len(data) != 0 {
+		t.Errorf("journal holds %q, want nothing", data)
+	}
+}
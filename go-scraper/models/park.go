@@ -1,12 +1,17 @@
 package models
 
 type Park struct {
-	Name       string         `json:"name"`
-	StateCode  string         `json:"stateCode"`
-	Address    string         `json:"address,omitempty"`
-	Latitude   float32        `json:"latitude"`
-	Longitude  float32        `json:"longitude"`
-	Activities []ParkActivity `json:"activities"`
+	Name         string         `json:"name"`
+	StateCode    string         `json:"stateCode"`
+	Address      string         `json:"address,omitempty"`
+	Telephone    string         `json:"telephone,omitempty"`
+	OpeningHours []string       `json:"openingHours,omitempty"`
+	Latitude     float32        `json:"latitude"`
+	Longitude    float32        `json:"longitude"`
+	Activities   []ParkActivity `json:"activities"`
+
+	// FieldSources names the extractor each field came from, when several were combined
+	FieldSources map[string]string `json:"fieldSources,omitempty"`
 }
 
 type ParkActivity struct {
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// These mirror the regexes of ToUrlFriendly in the API's PostGresParksRepository. .NET's \s also
+// matches Unicode separators, Go's doesn't, hence the explicit class.
+var (
+	urlWhitespace   = regexp.MustCompile(`[\f\n\r\t\v\x{85}\p{Z}]+`)
+	urlUnsafeChars  = regexp.MustCompile(`[^a-z0-9\-_]`)
+	urlRepeatedDash = regexp.MustCompile(`-+`)
+)
+
+// URLFriendly turns input into a slug the same way the API does: lowercase, whitespace runs
+// become hyphens, everything but letters, digits, hyphens and underscores is dropped, and
+// hyphens are collapsed and trimmed
+func URLFriendly(input string) string {
+	result := strings.TrimSpace(strings.ToLower(input))
+	result = urlWhitespace.ReplaceAllString(result, "-")
+	result = urlUnsafeChars.ReplaceAllString(result, "")
+	result = urlRepeatedDash.ReplaceAllString(result, "-")
+	return strings.Trim(result, "-")
+}
+
+// ParkCode returns the natural key the API stores a park under, e.g. "starved-rock-state-park-il"
+func ParkCode(park Park) string {
+	return URLFriendly(park.Name) + "-" + URLFriendly(park.StateCode)
+}
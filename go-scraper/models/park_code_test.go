@@ -0,0 +1,54 @@
+package models
+
+import "testing"
+
+// The expected values are what ToUrlFriendly in the API's PostGresParksRepository returns
+func TestURLFriendly(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Starved Rock State Park", "starved-rock-state-park"},
+		{"IL", "il"},
+		{"Lincoln's New Salem", "lincolns-new-salem"},
+		{"Fort Massac State Park & Campground", "fort-massac-state-park-campground"},
+		{"Rock  Cut -- State   Park", "rock-cut-state-park"},
+		{"Kickapoo - Middle Fork", "kickapoo-middle-fork"},
+		{"-Edge Park-", "edge-park"},
+		{"  Trimmed Park\t\n", "trimmed-park"},
+		{"Sam Dale_Lake", "sam-dale_lake"},
+		{"Château de Ramezay", "chteau-de-ramezay"},
+		{"Parc\u00a0Omega", "parc-omega"},
+		{"Line\u2028Separator", "line-separator"},
+		{"Ærø Park", "r-park"},
+		{"İstanbul Park", "istanbul-park"},
+		{"Park 66", "park-66"},
+		{"", ""},
+		{"   ", ""},
+		{"&&&", ""},
+		{"Épée", "pe"},
+	}
+
+	for _, test := range tests {
+		if got := URLFriendly(test.input); got != test.want {
+			t.Errorf("URLFriendly(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestParkCode(t *testing.T) {
+	tests := []struct {
+		park Park
+		want string
+	}{
+		{Park{Name: "Starved Rock State Park", StateCode: "IL"}, "starved-rock-state-park-il"},
+		{Park{Name: "Lincoln's New Salem", StateCode: "il"}, "lincolns-new-salem-il"},
+		{Park{Name: "???", StateCode: "IN"}, "-in"},
+	}
+
+	for _, test := range tests {
+		if got := ParkCode(test.park); got != test.want {
+			t.Errorf("ParkCode(%q, %q) = %q, want %q", test.park.Name, test.park.StateCode, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"scraper/events"
+	"scraper/writers"
+	"syscall"
+
+	"github.com/joho/godotenv"
+)
+
+// replayTarget is the writer that dead letters are replayed to
+type replayTarget struct {
+	name       string
+	subscriber events.ParkEventSubscriber
+	retry      events.RetryPolicy
+	// finish runs once every letter was handed to the subscriber, for writers that only deliver
+	// on Close. Its error counts like a subscriber's. May be nil.
+	finish func(ctx context.Context) error
+}
+
+// replayDeadLetters implements the replay-dlq command. It re-delivers the dead letters of one
+// writer to it, with the writer's usual retry policy. Events that fail again go back to the
+// dead-letter queue for the next replay.
+func replayDeadLetters(args []string) {
+	flags := flag.NewFlagSet("replay-dlq", flag.ExitOnError)
+	subscriberFlag := flags.String("subscriber", "", fmt.Sprintf("Writer to replay dead letters to: %s, %s or %s.", jsonWriterName, apiWriterName, postgresWriterName))
+	dirFlag := flags.String("dir", deadLetterDir, "Directory of the dead-letter files.")
+	flags.Parse(args)
+
+	// Load .env file (ignore error if file doesn't exist)
+	_ = godotenv.Load("config/.env")
+
+	if err := runReplay(*subscriberFlag, *dirFlag); err != nil {
+		log.Printf("%v", err)
+		os.Exit(1)
+	}
+}
+
+// runReplay creates the writer named subscriber and replays its dead letters in dir to it.
+// It returns its errors rather than exiting, so the writer is closed on every path.
+func runReplay(subscriber string, dir string) error {
+	deadLetters := events.NewDeadLetterQueue(dir)
+	target := replayTarget{name: subscriber}
+	switch subscriber {
+	case jsonWriterName:
+		target.subscriber, target.retry = writers.NewParkJSONWriter("data"), jsonWriterRetry
+	case apiWriterName:
+		apiWriter, lostAPIWrites, err := newAPIWriter(apiURLFromEnv(), deadLetters)
+		if err != nil {
+			return fmt.Errorf("failed to create API writer: %w", err)
+		}
+		target.subscriber, target.retry = apiWriter, apiWriterRetry
+		target.finish = func(ctx context.Context) error {
+			// The API writer buffers parks, they are only handled once it is closed
+			if err := apiWriter.Close(ctx); err != nil {
+				log.Printf("Gave up sending buffered parks to the API: %v", err)
+			}
+			apiStats := apiWriter.Stats()
+			fmt.Printf("API: %d parks created, %d updated, %d unchanged, %d failed\n", apiStats.Created, apiStats.Updated, apiStats.Unchanged, apiStats.Failed)
+
+			var errs []error
+			if lost := lostAPIWrites.Load(); lost > 0 {
+				errs = append(errs, fmt.Errorf("%w: %d parks", events.ErrNotDeadLettered, lost))
+			}
+			if apiStats.Failed > 0 {
+				errs = append(errs, fmt.Errorf("%d parks could not be written to the API", apiStats.Failed))
+			}
+			return errors.Join(errs...)
+		}
+	case postgresWriterName:
+		postgresWriter, err := writers.NewPostgresParkWriter(context.Background(), os.Getenv("DATABASE_URL"))
+		if err != nil {
+			return fmt.Errorf("failed to create database writer: %w", err)
+		}
+		defer postgresWriter.Close()
+		target.subscriber, target.retry = postgresWriter, postgresWriterRetry
+	default:
+		return fmt.Errorf("-subscriber must be %s, %s or %s, got %q", jsonWriterName, apiWriterName, postgresWriterName, subscriber)
+	}
+
+	// An interrupt abandons the deliveries in progress, which puts them back into the dead-letter queue
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return replay(ctx, target, deadLetters)
+}
+
+// replay re-delivers the dead letters of target from deadLetters to it. Letters that fail again
+// are dead-lettered again and reported in the error.
+func replay(ctx context.Context, target replayTarget, deadLetters *events.DeadLetterQueue) error {
+	letters, discard, err := deadLetters.Take(target.name)
+	if err != nil {
+		return fmt.Errorf("failed to read dead letters: %w", err)
+	}
+	if len(letters) == 0 {
+		fmt.Printf("No dead letters for %s in %s\n", target.name, filepath.Dir(deadLetters.Path(target.name)))
+		return discard()
+	}
+	fmt.Printf("Replaying %d dead letters to %s\n", len(letters), target.name)
+
+	publisher := events.NewParkEventPublisher(ctx)
+	err = publisher.SubscribeWithOptions(target.subscriber, events.QueueOptions{
+		Name:        target.name,
+		Retry:       target.retry,
+		DeadLetters: deadLetters,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe %s: %w", target.name, err)
+	}
+
+	for _, letter := range letters {
+		publisher.Publish(letter.Event)
+	}
+
+	// Every letter is now either handled or dead-lettered again, so the taken ones can go.
+	// If any couldn't be dead-lettered, all of them are kept for the next replay instead.
+	closeErr := publisher.Close()
+	if target.finish != nil {
+		closeErr = errors.Join(closeErr, target.finish(ctx))
+	}
+	if errors.Is(closeErr, events.ErrNotDeadLettered) {
+		return fmt.Errorf("some dead letters failed again and could not be put back, all of them will be replayed next time: %w", closeErr)
+	}
+	if err := discard(); err != nil {
+		log.Printf("%v", err)
+	}
+
+	if closeErr != nil {
+		return fmt.Errorf("some dead letters failed again and are back in %s: %w", deadLetters.Path(target.name), closeErr)
+	}
+	fmt.Printf("Replayed %d dead letters to %s\n", len(letters), target.name)
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"scraper/events"
+	"scraper/models"
+	"sync"
+	"testing"
+)
+
+// flakyWriter fails every park while failing is set and records the ones it accepts
+type flakyWriter struct {
+	mu       sync.Mutex
+	failing  bool
+	attempts int
+	written  []string
+}
+
+var errWriterDown = errors.New("writer is down")
+
+func (w *flakyWriter) OnParkScraped(ctx context.Context, event events.ParkScrapedEvent) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.attempts++
+	if w.failing {
+		return errWriterDown
+	}
+	w.written = append(w.written, event.URL)
+	return nil
+}
+
+func scrapedPark(url string) events.ParkScrapedEvent {
+	return events.ParkScrapedEvent{
+		Park:      &models.Park{Name: "Starved Rock State Park", StateCode: "IL"},
+		StateCode: "IL",
+		URL:       url,
+	}
+}
+
+// fileExists reports whether path exists, failing the test on any other error
+func fileExists(t *testing.T, path string) bool {
+	t.Helper()
+
+	_, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	if err != nil {
+		t.Fatalf("Stat(%s): %v", path, err)
+	}
+	return true
+}
+
+func TestReplayRedeliversDeadLetters(t *testing.T) {
+	deadLetters := events.NewDeadLetterQueue(t.TempDir())
+	writer := &flakyWriter{failing: true}
+	target := replayTarget{name: postgresWriterName, subscriber: writer, retry: events.RetryPolicy{MaxAttempts: 3}}
+	urls := []string{"https://dnr.illinois.gov/parks/park/starved-rock", "https://dnr.illinois.gov/parks/park/volo-bog"}
+
+	// A run whose writer is down dead-letters every park once its retries are used up
+	publisher := events.NewParkEventPublisher(context.Background())
+	err := publisher.SubscribeWithOptions(writer, events.QueueOptions{Name: target.name, Retry: target.retry, DeadLetters: deadLetters})
+	if err != nil {
+		t.Fatalf("SubscribeWithOptions: %v", err)
+	}
+	for _, url := range urls {
+		publisher.Publish(scrapedPark(url))
+	}
+	if err := publisher.Close(); !errors.Is(err, errWriterDown) || errors.Is(err, events.ErrNotDeadLettered) {
+		t.Fatalf("Close returned %v, want the dead-lettered failures", err)
+	}
+	if writer.attempts != 6 {
+		t.Errorf("made %d attempts, want 3 for each of the 2 parks", writer.attempts)
+	}
+	if !fileExists(t, deadLetters.Path(target.name)) {
+		t.Fatal("no dead-letter file was written")
+	}
+
+	// A replay while the writer is still down puts them back
+	if err := replay(context.Background(), target, deadLetters); !errors.Is(err, errWriterDown) {
+		t.Fatalf("replay returned %v, want the repeated failures", err)
+	}
+	// Taken letters that were never discarded come back with the next replay
+	letters, _, err := deadLetters.Take(target.name)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if len(letters) != 2 || letters[0].Attempts != 3 || letters[0].Event.(events.ParkScrapedEvent).URL != urls[0] {
+		t.Fatalf("got dead letters %+v, want both parks after 3 attempts each", letters)
+	}
+
+	// Once the writer is back, a replay delivers them and empties the dead-letter queue
+	writer.failing = false
+	if err := replay(context.Background(), target, deadLetters); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if !reflect.DeepEqual(writer.written, urls) {
+		t.Errorf("wrote %v, want %v", writer.written, urls)
+	}
+	path := deadLetters.Path(target.name)
+	if fileExists(t, path) || fileExists(t, path+".taken") {
+		t.Error("the replayed dead letters are still on disk")
+	}
+
+	// A second replay has nothing left to deliver
+	if err := replay(context.Background(), target, deadLetters); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(writer.written) != 2 {
+		t.Errorf("wrote %v after replaying an empty queue", writer.written)
+	}
+}
+
+func TestReplayKeepsLettersThatCouldNotBePutBack(t *testing.T) {
+	deadLetters := events.NewDeadLetterQueue(t.TempDir())
+	url := "https://dnr.illinois.gov/parks/park/starved-rock"
+	if err := deadLetters.Add(events.DeadLetter{Subscriber: apiWriterName, Event: scrapedPark(url), Err: "timeout", Attempts: 1}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// As when the API writer can't dead-letter a park it failed to send
+	target := replayTarget{
+		name:       apiWriterName,
+		subscriber: &flakyWriter{},
+		finish: func(ctx context.Context) error {
+			return events.ErrNotDeadLettered
+		},
+	}
+	if err := replay(context.Background(), target, deadLetters); !errors.Is(err, events.ErrNotDeadLettered) {
+		t.Fatalf("replay returned %v, want %v", err, events.ErrNotDeadLettered)
+	}
+
+	letters, _, err := deadLetters.Take(apiWriterName)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if len(letters) != 1 || letters[0].Event.(events.ParkScrapedEvent).URL != url {
+		t.Errorf("got dead letters %+v, want the replayed one kept", letters)
+	}
+}
+
+func TestRunReplayRejectsUnknownSubscriber(t *testing.T) {
+	if err := runReplay("SlackWriter", t.TempDir()); err == nil {
+		t.Error("runReplay accepted an unknown subscriber")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"scraper/events"
+	"sync"
+	"time"
+)
+
+// runSummaryLogger reports how a run goes from its lifecycle events: the states it covers, the
+// addresses of each state that could not be geocoded, and the totals once the run is over
+type runSummaryLogger struct {
+	out io.Writer
+
+	mu           sync.Mutex
+	notGeocoded  map[string][]events.GeocodeFailed // State code -> addresses that failed
+	geocodeTotal int
+}
+
+// runSummaryEvents are the event types a runSummaryLogger subscribes to
+var runSummaryEvents = []events.EventType{
+	events.RunStartedType,
+	events.GeocodeFailedType,
+	events.StateCompletedType,
+	events.RunCompletedType,
+}
+
+// newRunSummaryLogger creates a runSummaryLogger that writes to out
+func newRunSummaryLogger(out io.Writer) *runSummaryLogger {
+	return &runSummaryLogger{
+		out:         out,
+		notGeocoded: make(map[string][]events.GeocodeFailed),
+	}
+}
+
+// OnEvent handles a lifecycle event
+func (l *runSummaryLogger) OnEvent(ctx context.Context, event events.Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch e := event.(type) {
+	case events.RunStarted:
+		resumed := ""
+		if e.Resumed {
+			resumed = ", resuming an interrupted run"
+		}
+		fmt.Fprintf(l.out, "[RUN] Run %s started for %d states%s\n", e.RunID, len(e.States), resumed)
+	case events.GeocodeFailed:
+		l.notGeocoded[e.StateCode] = append(l.notGeocoded[e.StateCode], e)
+		l.geocodeTotal++
+	case events.StateCompleted:
+		failures := l.notGeocoded[e.StateCode]
+		delete(l.notGeocoded, e.StateCode)
+		if len(failures) == 0 {
+			return nil
+		}
+		fmt.Fprintf(l.out, "[RUN] %s: %d addresses could not be geocoded\n", e.StateCode, len(failures))
+		for _, failure := range failures {
+			if failure.URL == "" {
+				fmt.Fprintf(l.out, "[RUN]   %q: %v\n", failure.Address, failure.Err)
+			} else {
+				fmt.Fprintf(l.out, "[RUN]   %q on %s: %v\n", failure.Address, failure.URL, failure.Err)
+			}
+		}
+	case events.RunCompleted:
+		status := "finished"
+		if e.Interrupted {
+			status = "was interrupted"
+		}
+		fmt.Fprintf(l.out, "[RUN] Run %s %s after %v: %d parks scraped, %d failed, %d addresses not geocoded\n",
+			e.RunID, status, e.Duration.Round(time.Millisecond), e.Succeeded, e.Failed, l.geocodeTotal)
+	}
+	return nil
+}
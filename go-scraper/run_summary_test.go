@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"scraper/events"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunSummaryLogger(t *testing.T) {
+	var out strings.Builder
+	logger := newRunSummaryLogger(&out)
+
+	notFound := errors.New("no results")
+	for _, event := range []events.Event{
+		events.RunStarted{RunID: "run-1", States: []string{"IL", "IN"}, Resumed: true},
+		events.GeocodeFailed{StateCode: "IL", URL: "https://dnr.illinois.gov/parks/park.starved-rock.html", Address: "2678 E 875th Rd", Err: notFound},
+		events.GeocodeFailed{StateCode: "IN", Address: "1 Park Rd", Err: notFound},
+		events.StateCompleted{StateCode: "IL"},
+		events.StateCompleted{StateCode: "IN"},
+		events.RunCompleted{RunID: "run-1", Succeeded: 70, Failed: 2, Duration: 90 * time.Second},
+	} {
+		if err := logger.OnEvent(context.Background(), event); err != nil {
+			t.Fatalf("OnEvent(%s): %v", event.Type(), err)
+		}
+	}
+
+	want := `[RUN] Run run-1 started for 2 states, resuming an interrupted run
+[RUN] IL: 1 addresses could not be geocoded
+[RUN]   "2678 E 875th Rd" on https://dnr.illinois.gov/parks/park.starved-rock.html: no results
+[RUN] IN: 1 addresses could not be geocoded
+[RUN]   "1 Park Rd": no results
+[RUN] Run run-1 finished after 1m30s: 70 parks scraped, 2 failed, 2 addresses not geocoded
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
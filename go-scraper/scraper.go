@@ -1,28 +1,90 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"scraper/configHelper"
 	"scraper/events"
 	"scraper/extractors"
+	"scraper/journal"
 	"scraper/models"
 	"scraper/scrapers"
 	"scraper/services"
+	"scraper/transport"
 	"scraper/writers"
+	"sort"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// runJournalDir is where run journals are kept, one directory per run ID
+var runJournalDir = filepath.Join("data", "runs")
+
+// eventSpillDir is where subscriber queues that overflow to disk keep their events
+var eventSpillDir = filepath.Join("data", "spill")
+
+// deadLetterDir is where events go that a subscriber failed to handle, one file per subscriber
+var deadLetterDir = filepath.Join("data", "dead-letter")
+
+// Names of the writers, used in logs and to pick one for replay-dlq
+const (
+	jsonWriterName     = "JSONWriter"
+	apiWriterName      = "APIWriter"
+	postgresWriterName = "PostgresWriter"
+)
+
+// Retry policies of the writers. The API writer retries its requests itself, and only refuses
+// parks while its circuit breaker is open, so those are dead-lettered right away.
+var (
+	jsonWriterRetry     = events.RetryPolicy{MaxAttempts: 2, Backoff: 100 * time.Millisecond}
+	apiWriterRetry      = events.RetryPolicy{MaxAttempts: 1}
+	postgresWriterRetry = events.RetryPolicy{MaxAttempts: 4, Backoff: time.Second, MaxBackoff: 10 * time.Second}
+)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay-dlq" {
+		replayDeadLetters(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "seed-sql" {
+		generateSeedSQL(os.Args[2:])
+		return
+	}
+
 	// Parse command line arguments
 	statesFlag := flag.String("states", "", "Comma-separated list of state codes to scrape (e.g., 'IL,IN'). If empty, scrapes all states.")
+	concurrencyFlag := flag.Int("concurrency", 4, "Number of park pages to scrape in parallel per state. States can override this with 'concurrency' in urls.json.")
+	resumeFlag := flag.String("resume", "", "ID of an interrupted run to resume. Parks the run already delivered are not scraped again.")
+	cacheDirFlag := flag.String("cache-dir", filepath.Join("data", "http-cache"), "Directory for the HTTP response cache. Empty disables caching.")
+	cacheMaxAgeFlag := flag.Duration("cache-max-age", 0, "How long cached pages are used without asking the server. Older pages are revalidated with ETag/Last-Modified.")
+	offlineFlag := flag.Bool("offline", false, "Serve every page from the HTTP cache and never contact state park sites.")
+	recordFlag := flag.String("record", "", "Record every HTTP response of this run as fixtures in this directory (e.g. testdata/fixtures). Disables the HTTP cache.")
+	replayFlag := flag.String("replay", "", "Answer every HTTP request from fixtures recorded with -record in this directory instead of the network.")
+	staticOnlyFlag := flag.Bool("static-only", false, "Scrape only the 'urls' listed for each state in urls.json instead of gathering park URLs from the state's site.")
+	apiOverflowFlag := flag.String("api-overflow", "spill-to-disk", "What the API writer's event queue does when the API falls behind: block, drop-oldest or spill-to-disk (to data/spill).")
+	seedSQLFlag := flag.String("seed-sql", "", fmt.Sprintf("After the run, write seed SQL for the database to this file (e.g. %s), covering the parks already in data/ and the ones scraped now.", seedSQLPath))
+	noAPIFlag := flag.Bool("no-api", false, "Don't write parks to the API, e.g. when loading the database directly through DATABASE_URL.")
+	shutdownTimeoutFlag := flag.Duration("shutdown-timeout", 15*time.Second, "On SIGINT/SIGTERM, how long in-flight scrapes and queued events get to finish before they are abandoned.")
+	drainTimeoutFlag := flag.Duration("drain-timeout", 5*time.Minute, "After a run that wasn't interrupted, how long queued events and buffered API writes get to be delivered before they are abandoned and dead-lettered.")
 	flag.Parse()
 
+	// Stop starting new work on SIGINT/SIGTERM. A second signal kills the process as usual.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
 	// Load .env file (ignore error if file doesn't exist)
 	_ = godotenv.Load("config/.env")
 
@@ -45,24 +107,272 @@ func main() {
 		fmt.Println("No state filter provided, scraping all states")
 	}
 
+	// networkTransport is what finally answers requests: the network, the network with
+	// every response recorded as a fixture, or recorded fixtures only
+	var networkTransport http.RoundTripper = http.DefaultTransport
+	switch {
+	case *recordFlag != "" && *replayFlag != "":
+		log.Fatalf("-record and -replay can't be used together")
+	case *offlineFlag && (*recordFlag != "" || *replayFlag != ""):
+		log.Fatalf("-offline can't be combined with -record or -replay")
+	case *recordFlag != "":
+		fmt.Printf("Recording HTTP fixtures to %s\n", *recordFlag)
+		networkTransport = transport.NewRecordingTransport(*recordFlag, http.DefaultTransport)
+		*cacheDirFlag = ""
+	case *replayFlag != "":
+		fmt.Printf("Replaying HTTP fixtures from %s\n", *replayFlag)
+		networkTransport = transport.NewReplayTransport(*replayFlag)
+		*cacheDirFlag = ""
+	}
+
 	// Initialize geocoding service
 	mapboxAPIKey := os.Getenv("MAPBOX_API_KEY")
 	if mapboxAPIKey == "" {
 		log.Println("Warning: MAPBOX_API_KEY environment variable not set. Geocoding will not work.")
 	}
-	geocodingService := services.NewGeocodingService(mapboxAPIKey)
+	geocodingService := services.NewGeocodingServiceWithClient(mapboxAPIKey, &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: networkTransport,
+	})
+
+	// Every outbound request to state park sites goes through one shared client that
+	// honours robots.txt, answers from the HTTP cache when it can and throttles per host.
+	// Replayed fixtures don't need throttling.
+	limiter := transport.NewHostLimiter(transport.DefaultPolitenessPolicy)
+	registerPolitenessPolicies(urlConfig, limiter)
+	siteTransport := networkTransport
+	if *replayFlag == "" {
+		siteTransport = transport.NewPoliteTransport(limiter, networkTransport)
+	}
+	if *cacheDirFlag != "" {
+		cacheTransport, err := transport.NewCacheTransport(*cacheDirFlag, *cacheMaxAgeFlag, *offlineFlag, siteTransport)
+		if err != nil {
+			log.Fatalf("Failed to set up HTTP cache: %v", err)
+		}
+		siteTransport = cacheTransport
+	} else if *offlineFlag {
+		log.Fatalf("-offline needs an HTTP cache, -cache-dir must not be empty")
+	}
+	if *offlineFlag {
+		fmt.Printf("Offline mode: serving pages from %s only\n", *cacheDirFlag)
+	}
+	httpClient := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: transport.NewRobotsTransport(scrapers.UserAgent, limiter, siteTransport),
+	}
 
-	// Create extractor factory
-	extractorFactory := extractors.NewExtractorFactory(geocodingService)
+	// Create extractor factory and gatherer registry
+	extractorFactory := extractors.NewExtractorFactory(geocodingService, urlConfig)
+	gathererRegistry := scrapers.NewGathererRegistry()
+
+	// Create event publisher. Deliveries outlive ctx so queued events can still be flushed after an interrupt.
+	deliveryCtx, cancelDelivery := context.WithCancel(context.Background())
+	defer cancelDelivery()
+	publisher := events.NewParkEventPublisher(deliveryCtx)
+
+	// Addresses that can't be geocoded are reported with the state and park page they came from
+	geocodingService.OnGeocodeFailed(func(ctx context.Context, address string, err error) {
+		publisher.Publish(events.GeocodeFailed{
+			StateCode: stateCodeFrom(ctx),
+			URL:       scrapers.PageURL(ctx),
+			Address:   address,
+			Err:       err,
+			Timestamp: time.Now(),
+		})
+	})
 
-	// Create event publisher
-	publisher := events.NewParkEventPublisher()
-	defer publisher.Close()
+	// Lifecycle events are reported as the run goes, the per-park summary follows at the end
+	publisher.SubscribeEvents(newRunSummaryLogger(os.Stdout), runSummaryEvents...)
 
 	// Create and subscribe JSON writer
 	jsonWriter := writers.NewParkJSONWriter("data")
 
-	// Get API URL from environment variable, default to localhost
+	// Open the run journal, either for a new run or to pick up an interrupted one
+	var runJournal *journal.RunJournal
+	if *resumeFlag != "" {
+		runJournal, err = journal.Resume(runJournalDir, *resumeFlag)
+	} else {
+		runJournal, err = journal.Create(runJournalDir, journal.NewRunID())
+	}
+	if err != nil {
+		log.Fatalf("Failed to open run journal: %v", err)
+	}
+	defer runJournal.Close()
+	fmt.Printf("Run ID: %s (resume with -resume %s)\n", runJournal.RunID(), runJournal.RunID())
+
+	// Each writer gets its own queue, so a slow API doesn't hold up the JSON files
+	apiOverflow, err := events.ParseOverflowPolicy(*apiOverflowFlag)
+	if err != nil {
+		log.Fatalf("Invalid -api-overflow: %v", err)
+	}
+	// Events the writers still fail on after retrying are dead-lettered, replay them with replay-dlq
+	deadLetters := events.NewDeadLetterQueue(deadLetterDir)
+	err = publisher.SubscribeWithOptions(jsonWriter, events.QueueOptions{
+		Name:        jsonWriterName,
+		Retry:       jsonWriterRetry,
+		DeadLetters: deadLetters,
+	})
+	if err != nil {
+		log.Fatalf("Failed to subscribe JSON writer: %v", err)
+	}
+
+	var apiWriter *writers.APIParkWriter
+	var lostAPIWrites *atomic.Int64
+	if !*noAPIFlag {
+		apiWriter, lostAPIWrites, err = newAPIWriter(apiURLFromEnv(), deadLetters)
+		if err != nil {
+			log.Fatalf("Failed to create API writer: %v", err)
+		}
+		err = publisher.SubscribeWithOptions(apiWriter, events.QueueOptions{
+			Name:        apiWriterName,
+			Overflow:    apiOverflow,
+			SpillDir:    eventSpillDir,
+			Retry:       apiWriterRetry,
+			DeadLetters: deadLetters,
+		})
+		if err != nil {
+			log.Fatalf("Failed to subscribe API writer: %v", err)
+		}
+	}
+
+	// With DATABASE_URL set, parks are also written straight to the database
+	var postgresWriter *writers.PostgresParkWriter
+	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
+		postgresWriter, err = writers.NewPostgresParkWriter(ctx, databaseURL)
+		if err != nil {
+			log.Fatalf("Failed to create database writer: %v", err)
+		}
+		defer postgresWriter.Close()
+		err = publisher.SubscribeWithOptions(postgresWriter, events.QueueOptions{
+			Name:        postgresWriterName,
+			Retry:       postgresWriterRetry,
+			DeadLetters: deadLetters,
+		})
+		if err != nil {
+			log.Fatalf("Failed to subscribe database writer: %v", err)
+		}
+	}
+
+	// The seed starts from the parks earlier runs saved, so a partial or resumed run still gives a full seed
+	var seedWriter *writers.SeedSQLWriter
+	if *seedSQLFlag != "" {
+		seedWriter = writers.NewSeedSQLWriter()
+		if err := seedWriter.LoadDir(ctx, "data"); err != nil {
+			log.Fatalf("Failed to load saved parks for the seed: %v", err)
+		}
+		publisher.Subscribe(seedWriter)
+	}
+
+	// The journal goes last so a park only counts as done once every other subscriber has handled it, which for the
+	// API writer means written to the API, not just buffered. Parks that failed or were dropped are scraped again on -resume.
+	if err := publisher.SubscribeFinal(runJournal, events.QueueOptions{Name: "RunJournal"}); err != nil {
+		log.Fatalf("Failed to subscribe run journal: %v", err)
+	}
+
+	// Scrape parks for each state
+	run := &scrapeRun{
+		urlConfig:          urlConfig,
+		client:             httpClient,
+		extractors:         extractorFactory,
+		gatherers:          gathererRegistry,
+		publisher:          publisher,
+		journal:            runJournal,
+		resumed:            *resumeFlag != "",
+		defaultConcurrency: *concurrencyFlag,
+		staticOnly:         *staticOnlyFlag,
+		shutdownGrace:      *shutdownTimeoutFlag,
+	}
+	results := run.scrapeAllStates(ctx, statesToScrape)
+
+	// Wait until every subscriber has handled every event, but no longer than the drain timeout,
+	// or the shutdown timeout after an interrupt
+	drainTimeout := *drainTimeoutFlag
+	if ctx.Err() != nil {
+		fmt.Println("\nInterrupted, flushing queued events...")
+		drainTimeout = *shutdownTimeoutFlag
+	}
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancelFlush()
+	if err := publisher.Flush(flushCtx); err != nil {
+		log.Printf("Gave up waiting for queued events: %v", err)
+		cancelDelivery()
+	}
+	// The API writer finishes its parks before the publisher closes, so the journal still hears about them
+	if apiWriter != nil {
+		if err := apiWriter.Close(flushCtx); err != nil {
+			log.Printf("Gave up sending buffered parks to the API: %v", err)
+		}
+	}
+	if err := publisher.Close(); err != nil {
+		log.Printf("Event subscribers reported errors: %v", err)
+	}
+	if lostAPIWrites != nil {
+		if lost := lostAPIWrites.Load(); lost > 0 {
+			log.Printf("%d parks could not be written to the API nor dead-lettered", lost)
+		}
+	}
+
+	if seedWriter != nil {
+		if err := seedWriter.WriteFile(*seedSQLFlag); err != nil {
+			log.Printf("Failed to write seed SQL: %v", err)
+		} else {
+			fmt.Printf("Wrote seed SQL for %d parks to %s\n", seedWriter.Len(), *seedSQLFlag)
+		}
+	}
+
+	// Print summary
+	if ctx.Err() != nil {
+		fmt.Printf("\n=== Scraping Summary (partial, run was interrupted) ===\n")
+		defer fmt.Printf("\nResume with: -resume %s\n", runJournal.RunID())
+	} else {
+		fmt.Printf("\n=== Scraping Summary ===\n")
+	}
+	for _, result := range results {
+		printStateSummary(result)
+	}
+	if apiWriter != nil {
+		apiStats := apiWriter.Stats()
+		fmt.Printf("API: %d parks created, %d updated, %d unchanged, %d failed\n", apiStats.Created, apiStats.Updated, apiStats.Unchanged, apiStats.Failed)
+	}
+	if postgresWriter != nil {
+		dbStats := postgresWriter.Stats()
+		fmt.Printf("Database: %d parks created, %d updated\n", dbStats.Created, dbStats.Updated)
+	}
+}
+
+// newAPIWriter creates the API writer. Parks it accepted but could not write in the end are
+// dead-lettered like the ones its queue gives up on. The returned counter counts the parks that
+// could not be dead-lettered either, and are lost. Its credentials come from the environment.
+func newAPIWriter(apiURL string, deadLetters *events.DeadLetterQueue) (*writers.APIParkWriter, *atomic.Int64, error) {
+	auth, err := apiAuthFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+	apiWriter, err := writers.NewAPIParkWriter(apiURL, writers.APIWriterOptions{Auth: auth})
+	if err != nil {
+		return nil, nil, err
+	}
+	lost := &atomic.Int64{}
+
+	apiWriter.OnDeliveryFailed(func(event events.ParkScrapedEvent, err error) {
+		dlqErr := deadLetters.Add(events.DeadLetter{
+			Subscriber: apiWriterName,
+			Event:      event,
+			Err:        err.Error(),
+			Attempts:   1,
+			FailedAt:   time.Now(),
+		})
+		if dlqErr != nil {
+			lost.Add(1)
+			log.Printf("[%s] Failed to dead-letter park %s: %v", apiWriterName, event.URL, dlqErr)
+		}
+	})
+
+	return apiWriter, lost, nil
+}
+
+// apiURLFromEnv returns the API URL from the environment variable, defaulting to localhost
+func apiURLFromEnv() string {
 	apiURL := os.Getenv("API_URL")
 	if apiURL == "" {
 		apiURL = "http://localhost:8080"
@@ -70,27 +380,169 @@ func main() {
 	} else {
 		log.Printf("Using API_URL: %s", apiURL)
 	}
+	return apiURL
+}
 
-	apiWriter := writers.NewAPIParkWriter(apiURL)
-	publisher.Subscribe(jsonWriter)
-	publisher.Subscribe((apiWriter))
+// apiAuthFromEnv reads the API credentials from the environment, see config/.env.example.
+// Only the names of the credentials in use are logged, never their values.
+func apiAuthFromEnv() (writers.APIAuth, error) {
+	auth := writers.APIAuth{
+		APIKey:         os.Getenv("API_KEY"),
+		APIKeyHeader:   os.Getenv("API_KEY_HEADER"),
+		ClientCertFile: os.Getenv("API_CLIENT_CERT"),
+		ClientKeyFile:  os.Getenv("API_CLIENT_KEY"),
+		CACertFile:     os.Getenv("API_CA_CERT"),
+	}
 
-	// Scrape parks for each state
-	results := scrapeAllStates(urlConfig, extractorFactory, publisher, statesToScrape)
+	token, tokenFile := os.Getenv("API_TOKEN"), os.Getenv("API_TOKEN_FILE")
+	switch {
+	case token != "" && tokenFile != "":
+		return auth, fmt.Errorf("set either API_TOKEN or API_TOKEN_FILE, not both")
+	case token != "":
+		auth.Token = writers.StaticToken(token)
+	case tokenFile != "":
+		refresh := 5 * time.Minute
+		if value := os.Getenv("API_TOKEN_REFRESH"); value != "" {
+			var err error
+			refresh, err = time.ParseDuration(value)
+			if err != nil {
+				return auth, fmt.Errorf("invalid API_TOKEN_REFRESH: %w", err)
+			}
+		}
+		auth.Token = writers.NewFileTokenSource(tokenFile, refresh)
+	}
 
-	// Wait for all events to be processed
-	publisher.WaitForQueue()
+	var using []string
+	if auth.APIKey != "" {
+		using = append(using, "API key")
+	}
+	if auth.Token != nil {
+		using = append(using, "bearer token")
+	}
+	if auth.ClientCertFile != "" {
+		using = append(using, "client certificate")
+	}
+	if len(using) > 0 {
+		log.Printf("Authenticating to the API with: %s", strings.Join(using, ", "))
+	}
 
-	// Print summary
-	fmt.Printf("\n=== Scraping Summary ===\n")
-	for state, parks := range results {
-		fmt.Printf("%s: %d parks scraped\n", state, len(parks))
+	return auth, nil
+}
+
+// printStateSummary prints what happened to one state's parks
+func printStateSummary(result StateResult) {
+	scrape := result.Scrape
+	fmt.Printf("%s: %d parks scraped, %d failed, %d skipped, %d unfinished of %d gathered (%v)\n",
+		result.StateCode, len(scrape.Succeeded), len(scrape.Failed), len(scrape.Skipped), len(scrape.Unfinished),
+		scrape.Gathered, result.Duration.Round(time.Millisecond))
+
+	if result.Err != nil {
+		fmt.Printf("  error: %v\n", result.Err)
+	}
+	if result.PagesWalked > 0 {
+		fmt.Printf("  listing pages walked: %d\n", result.PagesWalked)
+	}
+	if result.LastModifiedKnown > 0 {
+		fmt.Printf("  last modified: %d of %d parks, oldest %s, newest %s\n",
+			result.LastModifiedKnown, len(scrape.Succeeded),
+			result.OldestModified.Format(time.DateOnly), result.NewestModified.Format(time.DateOnly))
+	}
+	for _, failure := range scrape.Failed {
+		fmt.Printf("  failed: %s: %v\n", failure.URL, failure.Err)
+	}
+	for _, url := range scrape.Skipped {
+		fmt.Printf("  skipped (disallowed by robots.txt): %s\n", url)
+	}
+	for _, scraped := range scrape.Succeeded {
+		if len(scraped.Warnings) > 0 {
+			fmt.Printf("  warnings: %s: %s\n", scraped.URL, strings.Join(scraped.Warnings, "; "))
+		}
+	}
+}
+
+// registerPolitenessPolicies applies each state's politeness settings to every host that state's URLs point at
+func registerPolitenessPolicies(urlConfig *configHelper.URLConfig, limiter *transport.HostLimiter) {
+	for _, stateCode := range urlConfig.GetAllStates() {
+		politeness, ok := urlConfig.GetPolitenessByState(stateCode)
+		if !ok {
+			continue
+		}
+
+		policy := transport.PolitenessPolicy{
+			RequestsPerSecond: politeness.RequestsPerSecond,
+			Burst:             politeness.Burst,
+			MinDelay:          time.Duration(politeness.MinDelayMS) * time.Millisecond,
+			Jitter:            time.Duration(politeness.JitterMS) * time.Millisecond,
+		}
+
+		stateURLs, _ := urlConfig.GetURLsByState(stateCode)
+		baseURL, _ := urlConfig.GetBaseURLByState(stateCode)
+		homePageURL, _ := urlConfig.GetHomePageURLByState(stateCode)
+
+		for _, rawURL := range append([]string{baseURL, homePageURL}, stateURLs...) {
+			parsed, err := url.Parse(rawURL)
+			if err != nil || parsed.Host == "" {
+				continue
+			}
+			limiter.SetPolicy(parsed.Host, policy)
+		}
+	}
+}
+
+// StateResult is the outcome of scraping one state
+type StateResult struct {
+	StateCode string
+	Scrape    scrapers.ScrapeResult // Empty if the state failed before any park was scraped
+	Err       error                 // Why the state failed or was cut short, nil if it completed
+	Duration  time.Duration
+
+	PagesWalked int // Listing pages or sitemaps the gatherer fetched, 0 if the URLs came from the journal
+
+	// When the scraped parks last changed according to the gatherer, e.g. a sitemap's <lastmod>.
+	// LastModifiedKnown is 0 when the gatherer doesn't say.
+	LastModifiedKnown int
+	OldestModified    time.Time
+	NewestModified    time.Time
+}
+
+// noteLastModified records the range of lastmod dates reporter gives for the scraped parks
+func (r *StateResult) noteLastModified(reporter scrapers.LastModifiedReporter) {
+	for _, scraped := range r.Scrape.Succeeded {
+		lastModified, ok := reporter.LastModified(scraped.URL)
+		if !ok {
+			continue
+		}
+		if r.LastModifiedKnown == 0 || lastModified.Before(r.OldestModified) {
+			r.OldestModified = lastModified
+		}
+		if r.LastModifiedKnown == 0 || lastModified.After(r.NewestModified) {
+			r.NewestModified = lastModified
+		}
+		r.LastModifiedKnown++
 	}
 }
 
-// scrapeAllStates takes the URL config and scrapes all parks for all states (or filtered states)
-func scrapeAllStates(urlConfig *configHelper.URLConfig, factory *extractors.ExtractorFactory, publisher *events.ParkEventPublisher, stateFilter []string) map[string][]*models.Park {
-	results := make(map[string][]*models.Park)
+// scrapeRun holds what every state of a run is scraped with
+type scrapeRun struct {
+	urlConfig          *configHelper.URLConfig
+	client             *http.Client
+	extractors         *extractors.ExtractorFactory
+	gatherers          *scrapers.GathererRegistry
+	publisher          *events.ParkEventPublisher
+	journal            *journal.RunJournal
+	resumed            bool
+	defaultConcurrency int
+	staticOnly         bool
+	shutdownGrace      time.Duration
+}
+
+// scrapeAllStates takes the URL config and scrapes all parks for all states (or filtered states),
+// returning one result per state it attempted, sorted by state code.
+// No new state is started once ctx is done. The run and each state are announced to the
+// publisher's lifecycle subscribers as they start and complete.
+func (r *scrapeRun) scrapeAllStates(ctx context.Context, stateFilter []string) []StateResult {
+	results := make([]StateResult, 0)
+	runStart := time.Now()
 
 	// Create a map for quick lookup if filtering
 	filterMap := make(map[string]bool)
@@ -100,88 +552,201 @@ func scrapeAllStates(urlConfig *configHelper.URLConfig, factory *extractors.Extr
 		}
 	}
 
-	for _, stateCode := range urlConfig.GetAllStates() {
+	allStates := r.urlConfig.GetAllStates()
+	sort.Strings(allStates)
+
+	stateCodes := make([]string, 0, len(allStates))
+	for _, stateCode := range allStates {
 		// Skip if not in filter (when filter is provided)
 		if len(stateFilter) > 0 && !filterMap[stateCode] {
 			fmt.Printf("Skipping %s (not in filter)\n", stateCode)
 			continue
 		}
+		stateCodes = append(stateCodes, stateCode)
+	}
 
-		baseURL, ok := urlConfig.GetBaseURLByState(stateCode)
-		if !ok || baseURL == "" {
-			log.Printf("No base URL found for state: %s, skipping", stateCode)
-			continue
+	r.publisher.Publish(events.RunStarted{
+		RunID:     r.journal.RunID(),
+		States:    stateCodes,
+		Resumed:   r.resumed,
+		Timestamp: runStart,
+	})
+
+	succeeded, failed := 0, 0
+	for _, stateCode := range stateCodes {
+		if ctx.Err() != nil {
+			break
 		}
 
-		homePageUrl, ok := urlConfig.GetHomePageURLByState(stateCode)
-
-		if !ok || homePageUrl == "" {
-			log.Printf("No homePage URL found for state: %s, skipping", stateCode)
-			continue
-		}
 		fmt.Printf("\n=== Scraping %s ===\n", stateCode)
-		parks := scrapeParksByState(stateCode, baseURL, homePageUrl, factory, publisher)
-		results[stateCode] = parks
+		startTime := time.Now()
+		r.publisher.Publish(events.StateStarted{StateCode: stateCode, Timestamp: startTime})
+
+		result := r.scrapeState(ctx, stateCode)
+		result.StateCode = stateCode
+		result.Duration = time.Since(startTime)
+		if result.Err != nil {
+			log.Printf("Failed to scrape parks for %s: %v", stateCode, result.Err)
+		}
+
+		r.publisher.Publish(events.StateCompleted{
+			StateCode:  stateCode,
+			Succeeded:  len(result.Scrape.Succeeded),
+			Failed:     len(result.Scrape.Failed),
+			Skipped:    len(result.Scrape.Skipped),
+			Unfinished: len(result.Scrape.Unfinished),
+			Duration:   result.Duration,
+			Err:        result.Err,
+			Timestamp:  time.Now(),
+		})
+		succeeded += len(result.Scrape.Succeeded)
+		failed += len(result.Scrape.Failed)
+
+		results = append(results, result)
 	}
 
+	r.publisher.Publish(events.RunCompleted{
+		RunID:       r.journal.RunID(),
+		Interrupted: ctx.Err() != nil,
+		Succeeded:   succeeded,
+		Failed:      failed,
+		Duration:    time.Since(runStart),
+		Timestamp:   time.Now(),
+	})
+
 	return results
 }
 
-// scrapeParksByState scrapes all parks for a given state
-func scrapeParksByState(stateCode string, baseUrl string, homePageUrl string, factory *extractors.ExtractorFactory, publisher *events.ParkEventPublisher) []*models.Park {
-	parks := make([]*models.Park, 0)
+// stateCodeKey is the context key under which scrapeParksByState stores the state being scraped
+type stateCodeKey struct{}
+
+// stateCodeFrom returns the state being scraped with ctx, or "" outside scrapeParksByState
+func stateCodeFrom(ctx context.Context) string {
+	stateCode, _ := ctx.Value(stateCodeKey{}).(string)
+	return stateCode
+}
+
+// scrapeState sets up the gatherer and concurrency of one state from its config and scrapes it
+func (r *scrapeRun) scrapeState(ctx context.Context, stateCode string) StateResult {
+	baseURL, ok := r.urlConfig.GetBaseURLByState(stateCode)
+	if !ok || baseURL == "" {
+		return StateResult{Err: fmt.Errorf("no base URL configured")}
+	}
+
+	// States without a gatherer fall back to their static urls, if they have any
+	staticURLs, _ := r.urlConfig.GetURLsByState(stateCode)
+	gathererConfig, ok := r.urlConfig.GetGathererByState(stateCode)
+	if r.staticOnly || (!ok && len(staticURLs) > 0) {
+		gathererConfig = configHelper.GathererConfig{Type: "static-list"}
+	} else if !ok {
+		return StateResult{Err: fmt.Errorf("no URL gatherer configured")}
+	}
+
+	// A static list doesn't need a page to gather from
+	homePageUrl, ok := r.urlConfig.GetHomePageURLByState(stateCode)
+	if (!ok || homePageUrl == "") && gathererConfig.Type != "static-list" {
+		return StateResult{Err: fmt.Errorf("no homePage URL configured")}
+	}
+
+	gatherer, err := r.gatherers.Create(scrapers.GathererSettings{
+		StateCode:  stateCode,
+		BaseURL:    baseURL,
+		Config:     gathererConfig,
+		StaticURLs: staticURLs,
+		Client:     r.client,
+	})
+	if err != nil {
+		return StateResult{Err: fmt.Errorf("failed to create URL gatherer: %w", err)}
+	}
+
+	concurrency := r.defaultConcurrency
+	if stateConcurrency, ok := r.urlConfig.GetConcurrencyByState(stateCode); ok {
+		concurrency = stateConcurrency
+	}
+
+	return r.scrapeParksByState(ctx, stateCode, homePageUrl, gatherer, concurrency)
+}
 
+// scrapeParksByState scrapes all parks for a given state. Each scraped park is published exactly once.
+func (r *scrapeRun) scrapeParksByState(ctx context.Context, stateCode string, homePageUrl string, gatherer scrapers.ParkUrlGatherer, concurrency int) StateResult {
 	// Get appropriate extractor for state using factory
-	extractor := factory.CreateExtractor(stateCode)
+	extractor := r.extractors.CreateExtractor(stateCode)
 	if extractor == nil {
-		log.Printf("No extractor found for state: %s", stateCode)
-		return parks
+		return StateResult{Err: fmt.Errorf("no extractor found")}
 	}
 
-	// Create appropriate URL gatherer based on state
-	var gatherer scrapers.ParkUrlGatherer
-	switch stateCode {
-	case "IL":
-		gatherer = scrapers.NewJSONParkUrlGatherer(baseUrl)
-	case "IN":
-		gatherer = scrapers.NewINHTMLParkUrlGatherer(baseUrl)
-	default:
-		log.Printf("No URL gatherer configured for state: %s", stateCode)
-		return parks
-	}
+	// Reuse the URLs a resumed run already gathered
+	gatherer = journal.NewResumingGatherer(r.journal, stateCode, gatherer)
 
-	// Create callback function for when a park is scraped
-	onParkScraped := func(park *models.Park, duration time.Duration, timestamp time.Time) {
-		// Validate park data
-		if park == nil {
-			log.Printf("Error: received nil park in callback")
-			return
-		}
+	// Lets callbacks deep in the scraper, like the geocoder's, tell which state they work for
+	ctx = context.WithValue(ctx, stateCodeKey{}, stateCode)
 
+	// Create callback function for when a park is scraped.
+	// Scraper workers run in parallel, so this must stay safe for concurrent use.
+	onParkScraped := func(park *models.Park, warnings []string, url string, duration time.Duration, timestamp time.Time) {
 		// Print park info with error handling for potentially invalid data
 		fmt.Printf("  ✓ %s (%.3f, %.3f) - %d activities - %v\n",
 			park.Name, park.Latitude, park.Longitude, len(park.Activities), duration)
+		for _, warning := range warnings {
+			fmt.Printf("    ! %s\n", warning)
+		}
 
 		// Publish event for scraped park
 		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("Error publishing park event for %s: %v", park.Name, r)
+			if recovered := recover(); recovered != nil {
+				log.Printf("Error publishing park event for %s: %v", park.Name, recovered)
 			}
 		}()
 
-		publisher.Publish(events.ParkScrapedEvent{
+		r.publisher.Publish(events.ParkScrapedEvent{
 			Park:      park,
 			StateCode: stateCode,
-			URL:       "", // URL not available in callback context
+			URL:       url,
 			Duration:  duration,
 			Timestamp: timestamp,
+			Warnings:  warnings,
 		})
 	}
 
 	// Create scraper
-	scraper := scrapers.NewBaseParkScraper(5, extractor, gatherer, onParkScraped)
+	scraper := scrapers.NewBaseParkScraper(5, concurrency, r.client, extractor, gatherer, onParkScraped)
+	scraper.SetShutdownGrace(r.shutdownGrace)
+	scraper.OnUrlsGathered(func(urls []string) {
+		r.publisher.Publish(events.UrlsGathered{
+			StateCode: stateCode,
+			URLs:      urls,
+			Timestamp: time.Now(),
+		})
+	})
+	scraper.OnParkFailed(func(failure scrapers.ParkFailure) {
+		if err := r.journal.RecordFailed(stateCode, failure.URL, failure.Err); err != nil {
+			log.Printf("Failed to journal failure of %s: %v", failure.URL, err)
+		}
+
+		r.publisher.Publish(events.ParkScrapeFailed{
+			StateCode: stateCode,
+			URL:       failure.URL,
+			Attempts:  failure.Attempts,
+			Err:       failure.Err,
+			Timestamp: time.Now(),
+		})
+	})
 
-	scraper.ScrapeAllParks(homePageUrl)
+	scrape, err := scraper.ScrapeAllParks(ctx, homePageUrl)
+	if errors.Is(err, transport.ErrDisallowedByRobots) {
+		log.Printf("Park listing for %s is disallowed by robots.txt: %v", stateCode, err)
+		return StateResult{Scrape: scrapers.ScrapeResult{Skipped: []string{homePageUrl}}, Err: err}
+	}
 
-	return parks
-}
\ No newline at end of file
+	result := StateResult{Err: err}
+	if scrape != nil {
+		result.Scrape = *scrape
+	}
+	if walker, ok := gatherer.(scrapers.PageWalker); ok {
+		result.PagesWalked = walker.PagesWalked()
+	}
+	if reporter, ok := gatherer.(scrapers.LastModifiedReporter); ok {
+		result.noteLastModified(reporter)
+	}
+	return result
+}
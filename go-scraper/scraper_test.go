@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"scraper/writers"
+	"testing"
+)
+
+func TestAPIAuthFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    writers.APIAuth
+		wantErr bool
+	}{
+		{"nothing set", nil, writers.APIAuth{}, false},
+		{"static token", map[string]string{"API_TOKEN": "env-token"}, writers.APIAuth{Token: writers.StaticToken("env-token")}, false},
+		{"key and header", map[string]string{"API_KEY": "secret-key", "API_KEY_HEADER": "X-Parks-Key"}, writers.APIAuth{APIKey: "secret-key", APIKeyHeader: "X-Parks-Key"}, false},
+		{"token and token file", map[string]string{"API_TOKEN": "env-token", "API_TOKEN_FILE": "config/token"}, writers.APIAuth{}, true},
+		{"invalid refresh", map[string]string{"API_TOKEN_FILE": "config/token", "API_TOKEN_REFRESH": "often"}, writers.APIAuth{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, name := range []string{"API_KEY", "API_KEY_HEADER", "API_TOKEN", "API_TOKEN_FILE", "API_TOKEN_REFRESH", "API_CLIENT_CERT", "API_CLIENT_KEY", "API_CA_CERT"} {
+				t.Setenv(name, test.env[name])
+			}
+
+			auth, err := apiAuthFromEnv()
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("got %+v, want an error", auth)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("apiAuthFromEnv: %v", err)
+			}
+			if !reflect.DeepEqual(auth, test.want) {
+				t.Errorf("got %+v, want %+v", auth, test.want)
+			}
+		})
+	}
+
+	t.Run("token file", func(t *testing.T) {
+		t.Setenv("API_TOKEN", "")
+		t.Setenv("API_TOKEN_FILE", "config/token")
+		auth, err := apiAuthFromEnv()
+		if err != nil {
+			t.Fatalf("apiAuthFromEnv: %v", err)
+		}
+		if _, ok := auth.Token.(*writers.FileTokenSource); !ok {
+			t.Errorf("got token source %T, want a *writers.FileTokenSource", auth.Token)
+		}
+	})
+}
@@ -1,77 +1,157 @@
 package scrapers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"net/http"
 	"scraper/extractors"
 	"scraper/models"
+	"scraper/transport"
+	"sync"
 	"time"
 
 	"github.com/gocolly/colly"
 )
 
-type BaseParkScraper struct{
-	waitMS int;
-	maxRetries int;
-	attemptNumber int;
-	userAgent string;
-	extractor extractors.ParkExtractor;
-	urlGatherer ParkUrlGatherer;
-	onParkScraped  func(park *models.Park, duration time.Duration, timestamp time.Time);
+type BaseParkScraper struct {
+	maxRetries    int
+	retryBackoff  time.Duration // Wait before the first retry of a page, doubled for each further one
+	maxBackoff    time.Duration
+	concurrency   int
+	shutdownGrace time.Duration
+	userAgent     string
+	client        *http.Client
+	extractor     extractors.ParkExtractor
+	urlGatherer   ParkUrlGatherer
+	onParkScraped func(park *models.Park, warnings []string, url string, duration time.Duration, timestamp time.Time)
+	onParkFailed  func(failure ParkFailure)
+	onGathered    func(urls []string)
 }
 
+// pageURLKey is the context key under which ScrapePark stores the URL of the page being scraped
+type pageURLKey struct{}
+
+// PageURL returns the URL of the park page being scraped with ctx, e.g. inside an extractor or
+// the geocoder it calls. It returns "" for contexts that did not come from ScrapePark.
+func PageURL(ctx context.Context) string {
+	url, _ := ctx.Value(pageURLKey{}).(string)
+	return url
+}
+
+// NewBaseParkScraper creates a scraper that visits up to concurrency park pages at a time through client,
+// trying each page up to maxRetries times, but at least once. ScrapeAllParks calls onParkScraped once for every park it scrapes. It may be called from several
+// goroutines at once and must be safe for concurrent use.
+func NewBaseParkScraper(maxRetries int, concurrency int, client *http.Client, extractor extractors.ParkExtractor, urlGatherer ParkUrlGatherer, onParkScraped func(park *models.Park, warnings []string, url string, duration time.Duration, timestamp time.Time)) *BaseParkScraper {
+	if maxRetries < 1 {
+		maxRetries = 1
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
 
-func NewBaseParkScraper(maxRetries int, extractor extractors.ParkExtractor, urlGatherer ParkUrlGatherer, onParkScraped func(park *models.Park, duration time.Duration, timestamp time.Time)) *BaseParkScraper {
 	return &BaseParkScraper{
-		waitMS: 1,
-		maxRetries: maxRetries,
-		attemptNumber: 1,
-		userAgent: "TripBuddyBot/1.0 (Educational Park Data Scraper; +https://github.com/nathangartlan2/tripbuddy-demo)",
-		extractor: extractor,
-		urlGatherer: urlGatherer,
+		maxRetries:    maxRetries,
+		retryBackoff:  500 * time.Millisecond,
+		maxBackoff:    10 * time.Second,
+		concurrency:   concurrency,
+		shutdownGrace: 10 * time.Second,
+		userAgent:     UserAgent,
+		client:        client,
+		extractor:     extractor,
+		urlGatherer:   urlGatherer,
 		onParkScraped: onParkScraped,
 	}
 }
 
+// OnParkFailed registers a callback for park pages that could not be scraped after all retries,
+// or yielded no park. It is not called for pages skipped because of robots.txt or cancellation.
+// Like onParkScraped, f must be safe for concurrent use.
+func (s *BaseParkScraper) OnParkFailed(f func(failure ParkFailure)) {
+	s.onParkFailed = f
+}
+
+// OnUrlsGathered registers a callback that ScrapeAllParks calls with the park URLs it is about to scrape
+func (s *BaseParkScraper) OnUrlsGathered(f func(urls []string)) {
+	s.onGathered = f
+}
+
+// SetShutdownGrace sets how long park pages that are already being scraped may keep going
+// after the context passed to ScrapeAllParks is cancelled
+func (s *BaseParkScraper) SetShutdownGrace(grace time.Duration) {
+	s.shutdownGrace = grace
+}
 
+// SetRetryBackoff sets how long ScrapePark waits before retrying a page, doubled for each
+// further retry up to maxBackoff. Each wait is jittered by up to half its length.
+func (s *BaseParkScraper) SetRetryBackoff(backoff time.Duration, maxBackoff time.Duration) {
+	s.retryBackoff = backoff
+	s.maxBackoff = maxBackoff
+}
 
-func (s *BaseParkScraper) ScrapePark(url string) (*models.Park, time.Duration, error) {
+// ScrapePark scrapes a single park page, retrying with backoff until it succeeds, maxRetries
+// is reached or ctx is done. warnings are the extractor's non-fatal problems with the page.
+// Unless ctx is done, errors are a *ParkScrapeError wrapping the error of the last attempt.
+func (s *BaseParkScraper) ScrapePark(ctx context.Context, url string) (*models.Park, []string, time.Duration, error) {
 
 	startTime := time.Now()
-	time.Sleep(time.Duration(s.waitMS) * time.Millisecond)
+	ctx = context.WithValue(ctx, pageURLKey{}, url)
+	var lastErr error
+
+	// Backoff state is per URL so concurrent workers don't slow each other down
+	backoff := s.retryBackoff
 
 	for i := 0; i < s.maxRetries; i++ {
+		if i > 0 {
+			wait := jitter(backoff)
+			fmt.Printf("  Waiting %v before retry...\n\n", wait.Round(time.Millisecond))
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return nil, nil, time.Since(startTime), ctx.Err()
+			}
+			backoff = min(backoff*2, s.maxBackoff)
+		}
 		fmt.Println("[SCRAPER] park details from:", url)
 
-		Park , err := s.scrapeParkInternal(url)
+		Park, warnings, err := s.scrapeParkInternal(ctx, url)
 
-		if err == nil {
-			elapsed := time.Since(startTime)
-			if(s.waitMS > 1){
-				s.waitMS /= 2
-			}
+		// Cancellation is not worth retrying either
+		if ctx.Err() != nil {
+			return nil, nil, time.Since(startTime), ctx.Err()
+		}
 
-			// Call callback if provided
-			if s.onParkScraped != nil {
-				s.onParkScraped(Park, elapsed, time.Now())
-			}
+		// Neither robots.txt, the offline cache, replayed fixtures nor the page's content will change between retries
+		var extractionErr *extractors.ExtractionError
+		if errors.Is(err, transport.ErrDisallowedByRobots) || errors.Is(err, transport.ErrNotCached) || errors.Is(err, transport.ErrNoFixture) || errors.As(err, &extractionErr) {
+			return nil, nil, time.Since(startTime), &ParkScrapeError{Attempts: i + 1, Err: err}
+		}
 
-			return Park, elapsed, nil
+		if err == nil {
+			return Park, warnings, time.Since(startTime), nil
 		} else {
+			lastErr = err
 			fmt.Printf("[Retry %d/%d] Error scraping URL: %s\n", i+1, s.maxRetries, url)
 			fmt.Printf("  Error: %v\n", err)
-			fmt.Printf("  Waiting %dms before retry...\n\n", s.waitMS)
-			s.waitMS *= 2
 		}
 	}
 
 	elapsed := time.Since(startTime)
-	return nil, elapsed, fmt.Errorf("failed to scrape park after %d retries", s.maxRetries)
+	return nil, nil, elapsed, &ParkScrapeError{Attempts: s.maxRetries, Err: lastErr}
 }
 
-func (s *BaseParkScraper) scrapeParkInternal(url string) (*models.Park, error) {
+func (s *BaseParkScraper) scrapeParkInternal(ctx context.Context, url string) (*models.Park, []string, error) {
 	cParkPage := colly.NewCollector()
+	cParkPage.WithTransport(transport.WithContext(ctx, s.client.Transport))
+	// Colly uses its own http.Client, so the shared client's timeout has to be applied to it
+	if s.client.Timeout > 0 {
+		cParkPage.SetRequestTimeout(s.client.Timeout)
+	}
 
 	var scrapedPark *models.Park
+	var warnings []string
+	var extractErr error
 
 	cParkPage.OnRequest(func(r *colly.Request) {
 		r.Headers.Set("User-Agent", s.userAgent)
@@ -79,56 +159,192 @@ func (s *BaseParkScraper) scrapeParkInternal(url string) (*models.Park, error) {
 
 	// Extract park details from individual park pages
 	cParkPage.OnHTML("body", func(e *colly.HTMLElement) {
-		scrapedPark = s.extractor.ExtractParkData(e)
+		scrapedPark, warnings, extractErr = s.extractor.ExtractParkData(ctx, e)
 	})
 
 	err := cParkPage.Visit(url)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if extractErr != nil {
+		return nil, nil, extractErr
 	}
 
-	return scrapedPark, nil
+	return scrapedPark, warnings, nil
 }
 
-// ScrapeAllParks uses the ParkUrlGatherer to collect all park URLs and then scrapes each one
-func (s *BaseParkScraper) ScrapeAllParks(mainPageUrl string) (*[]models.Park, error) {
+// ScrapeAllParks uses the ParkUrlGatherer to collect all park URLs and then scrapes them
+// with a bounded pool of workers. The result accounts for every gathered URL.
+//
+// Once ctx is cancelled no new park pages are started. Pages already in flight get the
+// shutdown grace period to finish, and the result so far is returned together with an
+// error wrapping ctx.Err().
+func (s *BaseParkScraper) ScrapeAllParks(ctx context.Context, mainPageUrl string) (*ScrapeResult, error) {
 	if s.urlGatherer == nil {
 		return nil, fmt.Errorf("urlGatherer is not set")
 	}
 
+	startTime := time.Now()
+
 	// Gather all park URLs from the main page
 	fmt.Printf("[SCRAPER] Gathering park URLs from: %s\n", mainPageUrl)
-	urls, err := s.urlGatherer.GatherUrls(mainPageUrl)
+	urls, err := s.urlGatherer.GatherUrls(ctx, mainPageUrl)
 	if err != nil {
 		return nil, fmt.Errorf("failed to gather URLs: %w", err)
 	}
 
-	fmt.Printf("[SCRAPER] Found %d park URLs to scrape\n", len(urls))
+	if s.onGathered != nil {
+		s.onGathered(urls)
+	}
+
+	workers := min(s.concurrency, len(urls))
+	fmt.Printf("[SCRAPER] Found %d park URLs to scrape with %d workers\n", len(urls), workers)
+
+	// Each worker writes only to its own index, so outcomes needs no locking.
+	// URLs that are never dispatched keep the zero outcome, parkUnfinished.
+	outcomes := make([]parkOutcome, len(urls))
+	jobs := make(chan int)
+
+	inflightCtx, cancelInflight := inflightContext(ctx, s.shutdownGrace)
+	defer cancelInflight()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				outcomes[i] = s.scrapeQueuedPark(inflightCtx, i, urls)
+			}
+		}()
+	}
 
-	// Scrape each park
-	parks := make([]models.Park, 0, len(urls))
-	for i, url := range urls {
-		fmt.Printf("[%d/%d] ", i+1, len(urls))
-		park, duration, err := s.ScrapePark(url)
-		if err != nil {
-			fmt.Printf("Failed to scrape %s: %v\n", url, err)
-			continue
+dispatch:
+	for i := range urls {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			fmt.Printf("[SCRAPER] Stopping, %d park URLs were not started\n", len(urls)-i)
+			break dispatch
 		}
+	}
+	close(jobs)
+	wg.Wait()
 
-		if(park == nil){
-			fmt.Printf("[SCRAPER] Issue scraping park at  %s parks. Skipping \n", url)
-			continue;
+	result := &ScrapeResult{
+		Gathered: len(urls),
+		Duration: time.Since(startTime),
+	}
+	for i, outcome := range outcomes {
+		url := urls[i]
+		switch outcome.status {
+		case parkSucceeded:
+			result.Succeeded = append(result.Succeeded, ScrapedPark{
+				URL:      url,
+				Park:     *outcome.park,
+				Warnings: outcome.warnings,
+				Duration: outcome.duration,
+			})
+		case parkFailed:
+			result.Failed = append(result.Failed, outcome.failure(url))
+		case parkSkipped:
+			result.Skipped = append(result.Skipped, url)
+		default:
+			result.Unfinished = append(result.Unfinished, url)
 		}
-		parks = append(parks, *park)
+	}
 
+	fmt.Printf("[SCRAPER] Successfully scraped %d/%d parks\n", len(result.Succeeded), len(urls))
+	if ctx.Err() != nil {
+		return result, fmt.Errorf("scrape interrupted: %w", ctx.Err())
+	}
+	return result, nil
+}
 
-		// Call callback if provided
-		if s.onParkScraped != nil {
-			s.onParkScraped(park, duration, time.Now())
-		}
+// jitter spreads a backoff of d over [d/2, 3d/2), so pages that failed together aren't all retried at once
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
 	}
+	return d/2 + rand.N(d)
+}
+
+// inflightContext returns a context for work that has already started when ctx is cancelled.
+// It outlives ctx by grace so that work can finish, and is cancelled after that.
+func inflightContext(ctx context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	inflight, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(grace, cancel)
+	})
+
+	return inflight, func() {
+		stop()
+		cancel()
+	}
+}
+
+// parkStatus is how far a park URL got in ScrapeAllParks
+type parkStatus int
+
+const (
+	parkUnfinished parkStatus = iota
+	parkSucceeded
+	parkFailed
+	parkSkipped
+)
 
-	fmt.Printf("[SCRAPER] Successfully scraped %d/%d parks\n", len(parks), len(urls))
-	return &parks, nil
+// parkOutcome is what a ScrapeAllParks worker made of one park URL
+type parkOutcome struct {
+	status   parkStatus
+	park     *models.Park
+	warnings []string
+	duration time.Duration
+	err      error
 }
 
+// failure describes a failed outcome for url
+func (o parkOutcome) failure(url string) ParkFailure {
+	attempts := 1
+	var scrapeErr *ParkScrapeError
+	if errors.As(o.err, &scrapeErr) {
+		attempts = scrapeErr.Attempts
+	}
+	return ParkFailure{URL: url, Attempts: attempts, Err: o.err}
+}
+
+// scrapeQueuedPark scrapes urls[i] on behalf of a ScrapeAllParks worker and reports the
+// park to onParkScraped or onParkFailed
+func (s *BaseParkScraper) scrapeQueuedPark(ctx context.Context, i int, urls []string) parkOutcome {
+	url := urls[i]
+	fmt.Printf("[%d/%d] Queued %s\n", i+1, len(urls), url)
+
+	park, warnings, duration, err := s.ScrapePark(ctx, url)
+	if errors.Is(err, transport.ErrDisallowedByRobots) {
+		fmt.Printf("[SCRAPER] Skipping %s: %v\n", url, err)
+		return parkOutcome{status: parkSkipped, err: err}
+	}
+	if err != nil && ctx.Err() != nil {
+		fmt.Printf("[SCRAPER] Gave up on %s: %v\n", url, err)
+		return parkOutcome{status: parkUnfinished, err: err}
+	}
+
+	if err == nil && park == nil {
+		fmt.Printf("[SCRAPER] Issue scraping park at  %s parks. Skipping \n", url)
+		err = fmt.Errorf("no park data extracted")
+	}
+	if err != nil {
+		fmt.Printf("Failed to scrape %s: %v\n", url, err)
+		outcome := parkOutcome{status: parkFailed, err: err}
+		if s.onParkFailed != nil {
+			s.onParkFailed(outcome.failure(url))
+		}
+		return outcome
+	}
+
+	// Call callback if provided
+	if s.onParkScraped != nil {
+		s.onParkScraped(park, warnings, url, duration, time.Now())
+	}
+
+	return parkOutcome{status: parkSucceeded, park: park, warnings: warnings, duration: duration}
+}
@@ -0,0 +1,171 @@
+package scrapers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"scraper/models"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+// pathExtractor builds a park named after the page's path
+type pathExtractor struct{}
+
+func (pathExtractor) ExtractParkData(ctx context.Context, e *colly.HTMLElement) (*models.Park, []string, error) {
+	return &models.Park{Name: e.Request.URL.Path, StateCode: "IL"}, nil, nil
+}
+
+// fixedGatherer returns the same URLs for every main page
+type fixedGatherer []string
+
+func (g fixedGatherer) GatherUrls(ctx context.Context, mainPageUrl string) ([]string, error) {
+	return g, nil
+}
+
+func TestScrapeAllParksLimitsConcurrencyAndAccountsForEveryURL(t *testing.T) {
+	const concurrency = 3
+
+	var inflight, maxInflight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			max := maxInflight.Load()
+			if n <= max || maxInflight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		if strings.HasPrefix(r.URL.Path, "/broken") {
+			http.Error(w, "broken", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, "<html><body>park</body></html>")
+	}))
+	defer server.Close()
+
+	var urls []string
+	for i := 0; i < 12; i++ {
+		urls = append(urls, fmt.Sprintf("%s/park/%d", server.URL, i))
+	}
+	urls = append(urls, server.URL+"/broken/1", server.URL+"/broken/2")
+
+	var mu sync.Mutex
+	reported := make(map[string]int)
+	scraper := NewBaseParkScraper(2, concurrency, server.Client(), pathExtractor{}, fixedGatherer(urls),
+		func(park *models.Park, warnings []string, url string, duration time.Duration, timestamp time.Time) {
+			mu.Lock()
+			reported[url]++
+			mu.Unlock()
+		})
+	scraper.SetRetryBackoff(time.Millisecond, time.Millisecond)
+	scraper.OnParkFailed(func(failure ParkFailure) {
+		mu.Lock()
+		reported[failure.URL]++
+		mu.Unlock()
+	})
+
+	result, err := scraper.ScrapeAllParks(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("ScrapeAllParks: %v", err)
+	}
+
+	if max := maxInflight.Load(); max > concurrency {
+		t.Errorf("%d pages were fetched at once, want at most %d", max, concurrency)
+	}
+	if len(result.Succeeded) != 12 || len(result.Failed) != 2 || len(result.Skipped) != 0 || len(result.Unfinished) != 0 {
+		t.Errorf("got %d succeeded, %d failed, %d skipped, %d unfinished, want 12, 2, 0, 0",
+			len(result.Succeeded), len(result.Failed), len(result.Skipped), len(result.Unfinished))
+	}
+	for _, failure := range result.Failed {
+		if failure.Attempts != 2 {
+			t.Errorf("%s was tried %d times, want 2", failure.URL, failure.Attempts)
+		}
+	}
+
+	// Every URL gets exactly one outcome and exactly one callback
+	seen := make(map[string]int)
+	for _, scraped := range result.Succeeded {
+		seen[scraped.URL]++
+	}
+	for _, failure := range result.Failed {
+		seen[failure.URL]++
+	}
+	for _, url := range urls {
+		if seen[url] != 1 {
+			t.Errorf("%s has %d outcomes, want 1", url, seen[url])
+		}
+		if reported[url] != 1 {
+			t.Errorf("%s was reported %d times, want 1", url, reported[url])
+		}
+	}
+}
+
+func TestScrapeParkAppliesClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := server.Client()
+	client.Timeout = 100 * time.Millisecond
+	scraper := NewBaseParkScraper(1, 1, client, pathExtractor{}, nil, nil)
+
+	start := time.Now()
+	_, _, _, err := scraper.ScrapePark(context.Background(), server.URL+"/stalled")
+	if err == nil {
+		t.Fatal("ScrapePark of a stalled page succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("ScrapePark took %v, the client timeout is %v", elapsed, client.Timeout)
+	}
+}
+
+func TestScrapeParkTriesAtLeastOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/broken") {
+			http.Error(w, "broken", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, "<html><body>park</body></html>")
+	}))
+	defer server.Close()
+
+	for _, maxRetries := range []int{0, -1} {
+		scraper := NewBaseParkScraper(maxRetries, 1, server.Client(), pathExtractor{}, nil, nil)
+
+		park, _, _, err := scraper.ScrapePark(context.Background(), server.URL+"/park/1")
+		if err != nil || park == nil || park.Name != "/park/1" {
+			t.Errorf("maxRetries %d: got %+v, %v, want the park", maxRetries, park, err)
+		}
+
+		_, _, _, err = scraper.ScrapePark(context.Background(), server.URL+"/broken/1")
+		var scrapeErr *ParkScrapeError
+		if !errors.As(err, &scrapeErr) || scrapeErr.Attempts != 1 || scrapeErr.Err == nil {
+			t.Errorf("maxRetries %d: got %v, want one failed attempt with its error", maxRetries, err)
+		}
+	}
+}
+
+func TestJitterStaysWithinHalfOfBackoff(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		wait := jitter(time.Second)
+		if wait < 500*time.Millisecond || wait >= 1500*time.Millisecond {
+			t.Fatalf("jitter(1s) = %v, want within [500ms, 1.5s)", wait)
+		}
+	}
+	if wait := jitter(0); wait != 0 {
+		t.Errorf("jitter(0) = %v, want 0", wait)
+	}
+}
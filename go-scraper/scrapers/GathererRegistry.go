@@ -0,0 +1,100 @@
+package scrapers
+
+import (
+	"fmt"
+	"net/http"
+	"scraper/configHelper"
+	"sort"
+)
+
+// GathererSettings is everything a GathererBuilder gets to build the gatherer of one state
+type GathererSettings struct {
+	StateCode  string
+	BaseURL    string
+	Config     configHelper.GathererConfig
+	StaticURLs []string     // The state's "urls" array
+	Client     *http.Client // Shared client for all requests to state park sites
+}
+
+// GathererBuilder builds a ParkUrlGatherer from a state's gatherer config
+type GathererBuilder func(settings GathererSettings) (ParkUrlGatherer, error)
+
+// GathererRegistry maps the gatherer types used in urls.json to the code that builds them
+type GathererRegistry struct {
+	builders map[string]GathererBuilder
+}
+
+// NewGathererRegistry creates a registry with the built-in gatherer types registered
+func NewGathererRegistry() *GathererRegistry {
+	registry := &GathererRegistry{
+		builders: make(map[string]GathererBuilder),
+	}
+
+	registry.Register("json-list", func(settings GathererSettings) (ParkUrlGatherer, error) {
+		pagination, err := paginationFor(settings.Config)
+		if err != nil {
+			return nil, err
+		}
+		return NewJSONListParkUrlGatherer(settings.BaseURL, settings.Config.JSONPath, pagination, settings.Client)
+	})
+	registry.Register("html-links", func(settings GathererSettings) (ParkUrlGatherer, error) {
+		pagination, err := paginationFor(settings.Config)
+		if err != nil {
+			return nil, err
+		}
+		return NewHTMLLinkParkUrlGatherer(settings.BaseURL, settings.Config.SectionSelector, settings.Config.HrefPattern, pagination, settings.Client)
+	})
+	registry.Register("sitemap", func(settings GathererSettings) (ParkUrlGatherer, error) {
+		return NewSitemapParkUrlGatherer(settings.BaseURL, settings.Config.HrefPattern, settings.Client)
+	})
+	registry.Register("static-list", func(settings GathererSettings) (ParkUrlGatherer, error) {
+		if len(settings.StaticURLs) == 0 {
+			return nil, fmt.Errorf("the state has no urls to use")
+		}
+		return NewStaticListParkUrlGatherer(settings.StaticURLs), nil
+	})
+
+	return registry
+}
+
+// Register makes a gatherer type available to urls.json, replacing any builder already registered for it
+func (r *GathererRegistry) Register(gathererType string, builder GathererBuilder) {
+	r.builders[gathererType] = builder
+}
+
+// Create builds the gatherer described by settings.Config. With MergeStatic set, the
+// state's static URLs are added to whatever that gatherer finds.
+func (r *GathererRegistry) Create(settings GathererSettings) (ParkUrlGatherer, error) {
+	builder, ok := r.builders[settings.Config.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown gatherer type %q for %s (known types: %v)", settings.Config.Type, settings.StateCode, r.Types())
+	}
+
+	gatherer, err := builder(settings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s gatherer for %s: %w", settings.Config.Type, settings.StateCode, err)
+	}
+
+	if settings.Config.MergeStatic && settings.Config.Type != "static-list" && len(settings.StaticURLs) > 0 {
+		gatherer = NewMergingParkUrlGatherer(gatherer, NewStaticListParkUrlGatherer(settings.StaticURLs))
+	}
+	return gatherer, nil
+}
+
+// Types returns the registered gatherer types
+func (r *GathererRegistry) Types() []string {
+	types := make([]string, 0, len(r.builders))
+	for gathererType := range r.builders {
+		types = append(types, gathererType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// paginationFor builds the pagination of a gatherer config, or returns nil if it has none
+func paginationFor(config configHelper.GathererConfig) (*Pagination, error) {
+	if config.Pagination == nil {
+		return nil, nil
+	}
+	return NewPaginationFromConfig(*config.Pagination)
+}
@@ -0,0 +1,121 @@
+package scrapers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"scraper/transport"
+
+	"github.com/gocolly/colly"
+)
+
+// HTMLLinkParkUrlGatherer implements ParkUrlGatherer by collecting links from an HTML listing page
+type HTMLLinkParkUrlGatherer struct {
+	userAgent       string
+	baseURL         string // Base URL to resolve relative links against
+	sectionSelector string
+	hrefPattern     *regexp.Regexp
+	client          *http.Client
+
+	pagination  *Pagination
+	pagesWalked int
+}
+
+// NewHTMLLinkParkUrlGatherer creates a gatherer that sends its requests through client and collects
+// the links inside elements matching sectionSelector whose href matches hrefPattern.
+// An empty sectionSelector searches the whole page, an empty hrefPattern accepts every link.
+// pagination may be nil for listings that fit on one page.
+func NewHTMLLinkParkUrlGatherer(baseURL string, sectionSelector string, hrefPattern string, pagination *Pagination, client *http.Client) (*HTMLLinkParkUrlGatherer, error) {
+	if sectionSelector == "" {
+		sectionSelector = "body"
+	}
+
+	pattern, err := regexp.Compile(hrefPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid href pattern: %w", err)
+	}
+
+	return &HTMLLinkParkUrlGatherer{
+		userAgent:       UserAgent,
+		baseURL:         baseURL,
+		sectionSelector: sectionSelector,
+		hrefPattern:     pattern,
+		client:          client,
+		pagination:      pagination,
+	}, nil
+}
+
+// GatherUrls fetches and parses the HTML from the main page URL, and the pages after it
+// when the listing is paginated, to extract park URLs
+func (g *HTMLLinkParkUrlGatherer) GatherUrls(ctx context.Context, mainPageUrl string) ([]string, error) {
+	urls := make([]string, 0)
+	seen := make(map[string]bool)
+	var page ListingPage
+
+	// Create collector
+	c := colly.NewCollector()
+	c.WithTransport(transport.WithContext(ctx, g.client.Transport))
+
+	// Set user agent
+	c.OnRequest(func(r *colly.Request) {
+		r.Headers.Set("User-Agent", g.userAgent)
+	})
+
+	// Keep the raw page so the pagination strategy can look for the next one
+	c.OnResponse(func(r *colly.Response) {
+		page.Header = *r.Headers
+		page.Body = r.Body
+	})
+
+	// Find the sections that list the parks
+	c.OnHTML(g.sectionSelector, func(section *colly.HTMLElement) {
+		// Find all links within this section
+		section.ForEach("a[href]", func(_ int, link *colly.HTMLElement) {
+			href := link.Attr("href")
+			if !g.hrefPattern.MatchString(href) {
+				return
+			}
+
+			fullURL := resolveParkURL(g.baseURL, href)
+			if !seen[fullURL] {
+				seen[fullURL] = true
+				urls = append(urls, fullURL)
+				page.NewURLs++
+			}
+		})
+	})
+
+	// Handle errors
+	c.OnError(func(r *colly.Response, err error) {
+		fmt.Printf("Error scraping %s: %v\n", r.Request.URL, err)
+	})
+
+	// Visit the URL and any pages after it
+	pages, err := g.pagination.walk(ctx, mainPageUrl, func(pageURL string) (ListingPage, error) {
+		page = ListingPage{URL: pageURL}
+		if err := c.Visit(pageURL); err != nil {
+			return ListingPage{}, fmt.Errorf("failed to visit URL: %w", err)
+		}
+		return page, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	g.pagesWalked = pages
+	if g.pagination != nil {
+		fmt.Printf("[SCRAPER] Walked %d listing pages\n", pages)
+	}
+
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no park URLs found in '%s' matching pattern '%s'", g.sectionSelector, g.hrefPattern)
+	}
+
+	return urls, nil
+}
+
+// PagesWalked returns how many listing pages the last GatherUrls call fetched
+func (g *HTMLLinkParkUrlGatherer) PagesWalked() int {
+	return g.pagesWalked
+}
@@ -0,0 +1,169 @@
+package scrapers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// JSONListParkUrlGatherer implements ParkUrlGatherer by reading park links out of a JSON listing
+type JSONListParkUrlGatherer struct {
+	userAgent string
+	baseURL   string // Optional base URL to resolve relative links against
+	jsonPath  []string
+	client    *http.Client
+
+	pagination  *Pagination
+	pagesWalked int
+}
+
+// NewJSONListParkUrlGatherer creates a gatherer that sends its requests through client and collects
+// the strings found at jsonPath. jsonPath is a dot separated list of object keys, where a key
+// ending in "[]" steps into every element of an array, e.g. "listItems[].meta.dynamicPageLink".
+// pagination may be nil for listings that fit in one response.
+func NewJSONListParkUrlGatherer(baseURL string, jsonPath string, pagination *Pagination, client *http.Client) (*JSONListParkUrlGatherer, error) {
+	if jsonPath == "" {
+		return nil, fmt.Errorf("json path is required")
+	}
+
+	segments := strings.Split(jsonPath, ".")
+	for _, segment := range segments {
+		if strings.TrimSuffix(segment, "[]") == "" {
+			return nil, fmt.Errorf("json path %q has an empty key", jsonPath)
+		}
+	}
+
+	return &JSONListParkUrlGatherer{
+		userAgent:  UserAgent,
+		baseURL:    baseURL,
+		jsonPath:   segments,
+		client:     client,
+		pagination: pagination,
+	}, nil
+}
+
+// GatherUrls fetches and parses the JSON from the main page URL, and the pages after it
+// when the listing is paginated, to extract park URLs
+func (g *JSONListParkUrlGatherer) GatherUrls(ctx context.Context, mainPageUrl string) ([]string, error) {
+	urls := make([]string, 0)
+	seen := make(map[string]bool)
+
+	pages, err := g.pagination.walk(ctx, mainPageUrl, func(pageURL string) (ListingPage, error) {
+		header, body, err := g.fetchPage(ctx, pageURL)
+		if err != nil {
+			return ListingPage{}, err
+		}
+
+		// Parse JSON
+		var document any
+		if err := json.Unmarshal(body, &document); err != nil {
+			return ListingPage{}, fmt.Errorf("failed to parse JSON: %w", err)
+		}
+
+		// Extract URLs found at the configured path
+		found := len(urls)
+		for _, link := range collectJSONStrings(document, g.jsonPath) {
+			if link != "" {
+				urls = appendUnique(urls, seen, []string{resolveParkURL(g.baseURL, link)})
+			}
+		}
+
+		return ListingPage{URL: pageURL, Header: header, Body: body, NewURLs: len(urls) - found}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	g.pagesWalked = pages
+	if g.pagination != nil {
+		fmt.Printf("[SCRAPER] Walked %d listing pages\n", pages)
+	}
+
+	return urls, nil
+}
+
+// PagesWalked returns how many listing pages the last GatherUrls call fetched
+func (g *JSONListParkUrlGatherer) PagesWalked() int {
+	return g.pagesWalked
+}
+
+// fetchPage downloads one page of the listing
+func (g *JSONListParkUrlGatherer) fetchPage(ctx context.Context, pageURL string) (http.Header, []byte, error) {
+	// Create HTTP request
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set user agent
+	req.Header.Set("User-Agent", g.userAgent)
+
+	// Execute request
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check status code
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	// Read response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return resp.Header, body, nil
+}
+
+// collectJSONStrings returns the strings among the values collectJSONValues finds
+func collectJSONStrings(value any, path []string) []string {
+	var found []string
+	for _, v := range collectJSONValues(value, path) {
+		if s, ok := v.(string); ok {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+// collectJSONValues walks value along path and returns every value it ends on.
+// Keys that are missing or hold the wrong type are skipped rather than treated as errors,
+// as listings commonly contain items without a link.
+func collectJSONValues(value any, path []string) []any {
+	if len(path) == 0 {
+		return []any{value}
+	}
+
+	object, ok := value.(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	key, isArray := strings.CutSuffix(path[0], "[]")
+	child, ok := object[key]
+	if !ok {
+		return nil
+	}
+
+	if !isArray {
+		return collectJSONValues(child, path[1:])
+	}
+
+	items, ok := child.([]any)
+	if !ok {
+		return nil
+	}
+
+	var found []any
+	for _, item := range items {
+		found = append(found, collectJSONValues(item, path[1:])...)
+	}
+	return found
+}
@@ -0,0 +1,281 @@
+package scrapers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"scraper/configHelper"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// defaultMaxPages caps how many listing pages are walked when the config doesn't say
+const defaultMaxPages = 50
+
+// ListingPage is one fetched page of a park listing, as seen by a PageStrategy
+type ListingPage struct {
+	URL     string
+	Header  http.Header
+	Body    []byte
+	NewURLs int // Park URLs found on this page that earlier pages didn't have
+}
+
+// PageStrategy finds the page that comes after a listing page
+type PageStrategy interface {
+	// NextPage returns the URL of the page after page, or "" when page is the last one
+	NextPage(page ListingPage) (string, error)
+}
+
+// FirstPageStrategy is implemented by strategies that also adjust the URL of the first page,
+// e.g. to ask for the same page size as the pages after it
+type FirstPageStrategy interface {
+	// FirstPage returns the URL to fetch instead of startURL
+	FirstPage(startURL string) (string, error)
+}
+
+// Pagination walks the pages of a paginated listing with a PageStrategy.
+// A nil *Pagination walks only the first page.
+type Pagination struct {
+	strategy PageStrategy
+	maxPages int
+}
+
+// NewPagination creates a Pagination that walks at most maxPages pages (defaultMaxPages if maxPages < 1)
+func NewPagination(strategy PageStrategy, maxPages int) *Pagination {
+	if maxPages < 1 {
+		maxPages = defaultMaxPages
+	}
+
+	return &Pagination{
+		strategy: strategy,
+		maxPages: maxPages,
+	}
+}
+
+// NewPaginationFromConfig builds the Pagination a urls.json "pagination" block describes
+func NewPaginationFromConfig(config configHelper.PaginationConfig) (*Pagination, error) {
+	var strategy PageStrategy
+	switch config.Type {
+	case "rel-next":
+		strategy = relNextStrategy{}
+	case "next-button":
+		if config.Selector == "" {
+			return nil, fmt.Errorf("next-button pagination needs a selector")
+		}
+		strategy = nextButtonStrategy{selector: config.Selector}
+	case "offset":
+		if config.Param == "" || config.PageSize < 1 {
+			return nil, fmt.Errorf("offset pagination needs a param and a positive pageSize")
+		}
+		strategy = offsetStrategy{param: config.Param, limitParam: config.LimitParam, pageSize: config.PageSize}
+	case "cursor":
+		if config.Param == "" || config.CursorPath == "" {
+			return nil, fmt.Errorf("cursor pagination needs a param and a cursorPath")
+		}
+		strategy = cursorStrategy{param: config.Param, cursorPath: strings.Split(config.CursorPath, ".")}
+	default:
+		return nil, fmt.Errorf("unknown pagination type %q", config.Type)
+	}
+
+	return NewPagination(strategy, config.MaxPages), nil
+}
+
+// walk fetches startURL and the pages after it until the strategy runs out of pages, a page
+// repeats or the page cap is reached, and returns how many pages were fetched.
+// If a page after the first one fails, the pages gathered so far are kept.
+func (p *Pagination) walk(ctx context.Context, startURL string, fetch func(pageURL string) (ListingPage, error)) (int, error) {
+	visited := make(map[string]bool)
+	pageURL := startURL
+	pages := 0
+
+	if p != nil {
+		if first, ok := p.strategy.(FirstPageStrategy); ok {
+			var err error
+			if pageURL, err = first.FirstPage(startURL); err != nil {
+				return 0, fmt.Errorf("invalid listing URL: %w", err)
+			}
+		}
+	}
+
+	for pageURL != "" && !visited[pageURL] {
+		if p != nil && pages == p.maxPages {
+			fmt.Printf("[SCRAPER] Stopped after %d listing pages, the listing may have more\n", pages)
+			break
+		}
+		visited[pageURL] = true
+
+		page, err := fetch(pageURL)
+		if err != nil {
+			if pages == 0 || ctx.Err() != nil {
+				return pages, err
+			}
+			fmt.Printf("Error fetching listing page %s, keeping the first %d pages: %v\n", pageURL, pages, err)
+			break
+		}
+		pages++
+
+		if p == nil {
+			break
+		}
+		pageURL, err = p.strategy.NextPage(page)
+		if err != nil {
+			fmt.Printf("Error finding the page after %s: %v\n", page.URL, err)
+			break
+		}
+	}
+
+	return pages, nil
+}
+
+// relNextStrategy follows rel="next" from the Link header or a <link>/<a> on the page
+type relNextStrategy struct{}
+
+func (relNextStrategy) NextPage(page ListingPage) (string, error) {
+	for _, header := range page.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			target, params, found := strings.Cut(link, ";")
+			if !found || !hasRelNext(params) {
+				continue
+			}
+			target = strings.Trim(strings.TrimSpace(target), "<>")
+			return resolveParkURL(page.URL, target), nil
+		}
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page.Body))
+	if err != nil {
+		// Not HTML, so the Link header was the only place to look
+		return "", nil
+	}
+	href, ok := doc.Find(`link[rel~="next"][href], a[rel~="next"][href]`).First().Attr("href")
+	if !ok || strings.TrimSpace(href) == "" {
+		return "", nil
+	}
+	return resolveParkURL(page.URL, strings.TrimSpace(href)), nil
+}
+
+// hasRelNext reports whether the parameters of a Link header entry include rel=next
+func hasRelNext(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if strings.EqualFold(name, "rel") {
+			for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+				if strings.EqualFold(rel, "next") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// nextButtonStrategy follows the href of the first element matching a CSS selector
+type nextButtonStrategy struct {
+	selector string
+}
+
+func (s nextButtonStrategy) NextPage(page ListingPage) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page.Body))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	href, ok := doc.Find(s.selector).First().Attr("href")
+	if !ok || strings.TrimSpace(href) == "" || strings.HasPrefix(href, "#") {
+		return "", nil
+	}
+	return resolveParkURL(page.URL, strings.TrimSpace(href)), nil
+}
+
+// offsetStrategy moves a numeric offset query parameter forward by pageSize
+// until a page has no new park URLs
+type offsetStrategy struct {
+	param      string
+	limitParam string
+	pageSize   int
+}
+
+// FirstPage sets the limit on the first page too, so the listing's default page size can't
+// make the offsets skip or repeat parks
+func (s offsetStrategy) FirstPage(startURL string) (string, error) {
+	if s.limitParam == "" {
+		return startURL, nil
+	}
+
+	first, err := url.Parse(startURL)
+	if err != nil {
+		return "", err
+	}
+	query := first.Query()
+	query.Set(s.limitParam, strconv.Itoa(s.pageSize))
+	first.RawQuery = query.Encode()
+	return first.String(), nil
+}
+
+func (s offsetStrategy) NextPage(page ListingPage) (string, error) {
+	if page.NewURLs == 0 {
+		return "", nil
+	}
+
+	next, err := url.Parse(page.URL)
+	if err != nil {
+		return "", err
+	}
+	query := next.Query()
+
+	offset := 0
+	if current := query.Get(s.param); current != "" {
+		offset, err = strconv.Atoi(current)
+		if err != nil {
+			return "", fmt.Errorf("%s is not a number: %q", s.param, current)
+		}
+	}
+
+	query.Set(s.param, strconv.Itoa(offset+s.pageSize))
+	if s.limitParam != "" {
+		query.Set(s.limitParam, strconv.Itoa(s.pageSize))
+	}
+	next.RawQuery = query.Encode()
+	return next.String(), nil
+}
+
+// cursorStrategy reads the next cursor from a field of a JSON page and passes it back
+// in a query parameter
+type cursorStrategy struct {
+	param      string
+	cursorPath []string
+}
+
+func (s cursorStrategy) NextPage(page ListingPage) (string, error) {
+	var document any
+	if err := json.Unmarshal(page.Body, &document); err != nil {
+		return "", fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	var cursor string
+	if values := collectJSONValues(document, s.cursorPath); len(values) > 0 {
+		switch v := values[0].(type) {
+		case string:
+			cursor = v
+		case float64:
+			cursor = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	if cursor == "" {
+		return "", nil
+	}
+
+	next, err := url.Parse(page.URL)
+	if err != nil {
+		return "", err
+	}
+	query := next.Query()
+	query.Set(s.param, cursor)
+	next.RawQuery = query.Encode()
+	return next.String(), nil
+}
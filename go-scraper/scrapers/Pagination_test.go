@@ -0,0 +1,290 @@
+package scrapers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"scraper/configHelper"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// requestLog records the request URIs a test server saw
+type requestLog struct {
+	mu   sync.Mutex
+	uris []string
+}
+
+func (l *requestLog) add(r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.uris = append(l.uris, r.URL.RequestURI())
+}
+
+func (l *requestLog) all() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.uris...)
+}
+
+// parkListing is a JSON listing page with one park per name
+func parkListing(names ...string) string {
+	items := ""
+	for i, name := range names {
+		if i > 0 {
+			items += ","
+		}
+		items += fmt.Sprintf(`{"link":"/parks/%s"}`, name)
+	}
+	return `{"items":[` + items + `]}`
+}
+
+func newPagination(t *testing.T, config configHelper.PaginationConfig) *Pagination {
+	t.Helper()
+
+	pagination, err := NewPaginationFromConfig(config)
+	if err != nil {
+		t.Fatalf("NewPaginationFromConfig(%+v): %v", config, err)
+	}
+	return pagination
+}
+
+// gatherJSON gathers the links at items[].link from server with pagination
+func gatherJSON(t *testing.T, server *httptest.Server, pagination *Pagination, startPath string) ([]string, int) {
+	t.Helper()
+
+	gatherer, err := NewJSONListParkUrlGatherer("", "items[].link", pagination, server.Client())
+	if err != nil {
+		t.Fatalf("NewJSONListParkUrlGatherer: %v", err)
+	}
+	urls, err := gatherer.GatherUrls(context.Background(), server.URL+startPath)
+	if err != nil {
+		t.Fatalf("GatherUrls: %v", err)
+	}
+	return urls, gatherer.PagesWalked()
+}
+
+func TestRelNextFollowsLinkHeader(t *testing.T) {
+	var log requestLog
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Add("Link", `</parks.json?page=1>; rel="first"`)
+			w.Header().Add("Link", `</parks.json?page=2>; rel="next", </parks.json?page=3>; rel="last"`)
+			fmt.Fprint(w, parkListing("starved-rock", "volo-bog"))
+		case "2":
+			w.Header().Set("Link", `<?page=3>; rel="prev next"`)
+			fmt.Fprint(w, parkListing("chain-o-lakes"))
+		case "3":
+			w.Header().Set("Link", `</parks.json?page=2>; rel="prev"`)
+			fmt.Fprint(w, parkListing("beall-woods"))
+		}
+	}))
+	defer server.Close()
+
+	urls, pages := gatherJSON(t, server, newPagination(t, configHelper.PaginationConfig{Type: "rel-next"}), "/parks.json")
+
+	if want := []string{"/parks/starved-rock", "/parks/volo-bog", "/parks/chain-o-lakes", "/parks/beall-woods"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("got URLs %v, want %v", urls, want)
+	}
+	if pages != 3 {
+		t.Errorf("walked %d pages, want 3", pages)
+	}
+	if want := []string{"/parks.json", "/parks.json?page=2", "/parks.json?page=3"}; !reflect.DeepEqual(log.all(), want) {
+		t.Errorf("requested %v, want %v", log.all(), want)
+	}
+}
+
+// htmlListing is a listing page with park links and an optional next button
+func htmlListing(next string, parks ...string) string {
+	body := `<html><body><section class="parks">`
+	for _, park := range parks {
+		body += fmt.Sprintf(`<a href="/parks/%s">%s</a>`, park, park)
+	}
+	body += `</section>`
+	if next != "" {
+		body += fmt.Sprintf(`<nav><a class="pager-next" href="%s">Next</a></nav>`, next)
+	}
+	return body + `</body></html>`
+}
+
+func TestNextButtonFollowsSelector(t *testing.T) {
+	var log requestLog
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		switch r.URL.Path {
+		case "/parks":
+			fmt.Fprint(w, htmlListing("/parks/page/2", "starved-rock", "volo-bog"))
+		case "/parks/page/2":
+			fmt.Fprint(w, htmlListing("#", "chain-o-lakes"))
+		}
+	}))
+	defer server.Close()
+
+	pagination := newPagination(t, configHelper.PaginationConfig{Type: "next-button", Selector: "a.pager-next"})
+	gatherer, err := NewHTMLLinkParkUrlGatherer(server.URL, "section.parks", "^/parks/", pagination, server.Client())
+	if err != nil {
+		t.Fatalf("NewHTMLLinkParkUrlGatherer: %v", err)
+	}
+	urls, err := gatherer.GatherUrls(context.Background(), server.URL+"/parks")
+	if err != nil {
+		t.Fatalf("GatherUrls: %v", err)
+	}
+
+	want := []string{server.URL + "/parks/starved-rock", server.URL + "/parks/volo-bog", server.URL + "/parks/chain-o-lakes"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("got URLs %v, want %v", urls, want)
+	}
+	if gatherer.PagesWalked() != 2 {
+		t.Errorf("walked %d pages, want 2", gatherer.PagesWalked())
+	}
+	if want := []string{"/parks", "/parks/page/2"}; !reflect.DeepEqual(log.all(), want) {
+		t.Errorf("requested %v, want %v", log.all(), want)
+	}
+}
+
+func TestOffsetStopsOnPageWithoutNewURLs(t *testing.T) {
+	parks := []string{"a", "b", "c", "d", "e"}
+
+	var log requestLog
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		// Without a limit the listing would send its default of 10 parks per page
+		limit := 10
+		if value := r.URL.Query().Get("limit"); value != "" {
+			limit, _ = strconv.Atoi(value)
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+		// Past the end the listing repeats its last page instead of returning nothing
+		offset = min(offset, len(parks)-1)
+		fmt.Fprint(w, parkListing(parks[offset:min(offset+limit, len(parks))]...))
+	}))
+	defer server.Close()
+
+	pagination := newPagination(t, configHelper.PaginationConfig{Type: "offset", Param: "offset", LimitParam: "limit", PageSize: 2})
+	urls, pages := gatherJSON(t, server, pagination, "/parks.json?region=north")
+
+	if want := []string{"/parks/a", "/parks/b", "/parks/c", "/parks/d", "/parks/e"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("got URLs %v, want %v", urls, want)
+	}
+	if pages != 4 {
+		t.Errorf("walked %d pages, want 4", pages)
+	}
+
+	// The first page asks for the page size too
+	want := []string{
+		"/parks.json?limit=2&region=north",
+		"/parks.json?limit=2&offset=2&region=north",
+		"/parks.json?limit=2&offset=4&region=north",
+		"/parks.json?limit=2&offset=6&region=north",
+	}
+	if !reflect.DeepEqual(log.all(), want) {
+		t.Errorf("requested %v, want %v", log.all(), want)
+	}
+}
+
+func TestCursorPassesNextCursorBack(t *testing.T) {
+	var log requestLog
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		switch r.URL.Query().Get("after") {
+		case "":
+			fmt.Fprint(w, `{"items":[{"link":"/parks/a"}],"meta":{"next":"abc"}}`)
+		case "abc":
+			fmt.Fprint(w, `{"items":[{"link":"/parks/b"}],"meta":{"next":42}}`)
+		case "42":
+			fmt.Fprint(w, `{"items":[{"link":"/parks/c"}],"meta":{"next":null}}`)
+		}
+	}))
+	defer server.Close()
+
+	pagination := newPagination(t, configHelper.PaginationConfig{Type: "cursor", Param: "after", CursorPath: "meta.next"})
+	urls, pages := gatherJSON(t, server, pagination, "/parks.json")
+
+	if want := []string{"/parks/a", "/parks/b", "/parks/c"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("got URLs %v, want %v", urls, want)
+	}
+	if pages != 3 {
+		t.Errorf("walked %d pages, want 3", pages)
+	}
+	if want := []string{"/parks.json", "/parks.json?after=abc", "/parks.json?after=42"}; !reflect.DeepEqual(log.all(), want) {
+		t.Errorf("requested %v, want %v", log.all(), want)
+	}
+}
+
+func TestPaginationStopsAtMaxPages(t *testing.T) {
+	// Every page links to one more, forever
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		w.Header().Set("Link", fmt.Sprintf(`</parks.json?page=%d>; rel="next"`, page+1))
+		fmt.Fprint(w, parkListing(strconv.Itoa(page)))
+	}))
+	defer server.Close()
+
+	pagination := newPagination(t, configHelper.PaginationConfig{Type: "rel-next", MaxPages: 3})
+	urls, pages := gatherJSON(t, server, pagination, "/parks.json")
+
+	if pages != 3 {
+		t.Errorf("walked %d pages, want the cap of 3", pages)
+	}
+	if want := []string{"/parks/0", "/parks/1", "/parks/2"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("got URLs %v, want %v", urls, want)
+	}
+}
+
+func TestPaginationStopsOnRepeatedPage(t *testing.T) {
+	var log requestLog
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		// The last page links back to the first
+		next := map[string]string{"": "2", "2": "3", "3": "1"}[r.URL.Query().Get("page")]
+		if next == "1" {
+			w.Header().Set("Link", `</parks.json>; rel="next"`)
+		} else {
+			w.Header().Set("Link", fmt.Sprintf(`</parks.json?page=%s>; rel="next"`, next))
+		}
+		fmt.Fprint(w, parkListing("p"+r.URL.Query().Get("page")))
+	}))
+	defer server.Close()
+
+	_, pages := gatherJSON(t, server, newPagination(t, configHelper.PaginationConfig{Type: "rel-next"}), "/parks.json")
+
+	if pages != 3 {
+		t.Errorf("walked %d pages, want 3", pages)
+	}
+	if want := []string{"/parks.json", "/parks.json?page=2", "/parks.json?page=3"}; !reflect.DeepEqual(log.all(), want) {
+		t.Errorf("requested %v, want %v", log.all(), want)
+	}
+}
+
+func TestMergingGathererAddsUpPagesWalked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", `</parks.json?page=2>; rel="next"`)
+		}
+		fmt.Fprint(w, parkListing("p"+r.URL.Query().Get("page")))
+	}))
+	defer server.Close()
+
+	listing, err := NewJSONListParkUrlGatherer("", "items[].link", newPagination(t, configHelper.PaginationConfig{Type: "rel-next"}), server.Client())
+	if err != nil {
+		t.Fatalf("NewJSONListParkUrlGatherer: %v", err)
+	}
+	var gatherer ParkUrlGatherer = NewMergingParkUrlGatherer(listing, NewStaticListParkUrlGatherer([]string{"/parks/static"}))
+	if _, err := gatherer.GatherUrls(context.Background(), server.URL+"/parks.json"); err != nil {
+		t.Fatalf("GatherUrls: %v", err)
+	}
+
+	walker, ok := gatherer.(PageWalker)
+	if !ok {
+		t.Fatal("the merging gatherer doesn't report pages walked")
+	}
+	if walker.PagesWalked() != 2 {
+		t.Errorf("walked %d pages, want 2", walker.PagesWalked())
+	}
+}
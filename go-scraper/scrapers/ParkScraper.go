@@ -1,9 +1,16 @@
 package scrapers
 
-import "scraper/models"
+import (
+	"context"
+	"scraper/models"
+	"time"
+)
+
+// UserAgent identifies the scraper to state park sites and is matched against their robots.txt
+const UserAgent = "TripBuddyBot/1.0 (Educational Park Data Scraper; +https://github.com/nathangartlan2/tripbuddy-demo)"
 
 type ParkScraper interface {
-	 ScrapePark(url string) (*models.Park, error)
+	 ScrapePark(ctx context.Context, url string) (*models.Park, []string, time.Duration, error)
 
-	 ScrapeAllParks(url string) (*[] models.Park, error)
+	 ScrapeAllParks(ctx context.Context, url string) (*ScrapeResult, error)
 }
\ No newline at end of file
@@ -1,7 +1,46 @@
 package scrapers
 
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
 // ParkUrlGatherer defines the interface for gathering park URLs from a main state park page
 type ParkUrlGatherer interface {
-	// GatherUrls takes a main state park page URL and returns a list of individual park page URLs
-	GatherUrls(mainPageUrl string) ([]string, error)
+	// GatherUrls takes a main state park page URL and returns a list of individual park page URLs.
+	// It stops and returns ctx.Err() once ctx is done.
+	GatherUrls(ctx context.Context, mainPageUrl string) ([]string, error)
+}
+
+// LastModifiedReporter is implemented by gatherers whose source says when each park page last
+// changed, such as a sitemap's <lastmod>. Callers can use it to skip pages that haven't changed.
+type LastModifiedReporter interface {
+	// LastModified returns when pageURL last changed. ok is false when that is not known.
+	LastModified(pageURL string) (lastModified time.Time, ok bool)
+}
+
+// PageWalker is implemented by gatherers that read a listing spread over several pages or files
+type PageWalker interface {
+	// PagesWalked returns how many listing pages the last GatherUrls call fetched
+	PagesWalked() int
+}
+
+// resolveParkURL turns a link found on a listing into an absolute URL using baseURL.
+// Absolute links, and any link when baseURL is empty, are returned unchanged.
+func resolveParkURL(baseURL string, link string) string {
+	if baseURL == "" {
+		return link
+	}
+
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	return base.ResolveReference(ref).String()
 }
@@ -0,0 +1,50 @@
+package scrapers
+
+import (
+	"fmt"
+	"scraper/models"
+	"time"
+)
+
+// ScrapedPark is a park page that was scraped successfully
+type ScrapedPark struct {
+	URL      string
+	Park     models.Park
+	Warnings []string // Non-fatal problems the extractor had with the page
+	Duration time.Duration
+}
+
+// ParkFailure is a park page that could not be scraped
+type ParkFailure struct {
+	URL      string
+	Attempts int // How often the page was tried, 0 if it never was
+	Err      error
+}
+
+// ParkScrapeError is returned by ScrapePark when it gives up on a page
+type ParkScrapeError struct {
+	Attempts int
+	Err      error // Error of the last attempt
+}
+
+func (e *ParkScrapeError) Error() string {
+	if e.Attempts == 1 {
+		return fmt.Sprintf("failed to scrape park: %v", e.Err)
+	}
+	return fmt.Sprintf("failed to scrape park after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *ParkScrapeError) Unwrap() error {
+	return e.Err
+}
+
+// ScrapeResult is the outcome of ScrapeAllParks. Every gathered URL ends up in exactly one
+// of Succeeded, Failed, Skipped or Unfinished, each in the order the URLs were gathered.
+type ScrapeResult struct {
+	Succeeded  []ScrapedPark
+	Failed     []ParkFailure
+	Skipped    []string // Disallowed by robots.txt
+	Unfinished []string // Not scraped because the run was cancelled
+	Gathered   int
+	Duration   time.Duration
+}
@@ -0,0 +1,193 @@
+package scrapers
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSitemaps bounds how many sitemap files one GatherUrls call fetches, in case an index
+// is huge or sitemaps refer to each other in a loop
+const maxSitemaps = 500
+
+// SitemapParkUrlGatherer implements ParkUrlGatherer by reading an XML sitemap or sitemap index.
+// Nested indexes are followed and gzipped sitemaps are decompressed.
+type SitemapParkUrlGatherer struct {
+	userAgent  string
+	baseURL    string // Base URL to resolve relative locations against
+	urlPattern *regexp.Regexp
+	client     *http.Client
+
+	pagesWalked int
+
+	lastModifiedMu sync.Mutex
+	lastModified   map[string]time.Time
+}
+
+// sitemapDocument covers both a <urlset> and a <sitemapindex>
+type sitemapDocument struct {
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+	URLs     []sitemapEntry `xml:"url"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// NewSitemapParkUrlGatherer creates a gatherer that sends its requests through client and keeps
+// the page URLs matching urlPattern. An empty urlPattern keeps every page.
+func NewSitemapParkUrlGatherer(baseURL string, urlPattern string, client *http.Client) (*SitemapParkUrlGatherer, error) {
+	pattern, err := regexp.Compile(urlPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url pattern: %w", err)
+	}
+
+	return &SitemapParkUrlGatherer{
+		userAgent:    UserAgent,
+		baseURL:      baseURL,
+		urlPattern:   pattern,
+		client:       client,
+		lastModified: make(map[string]time.Time),
+	}, nil
+}
+
+// GatherUrls reads the sitemap at mainPageUrl, following sitemap indexes, and returns the
+// matching page URLs
+func (g *SitemapParkUrlGatherer) GatherUrls(ctx context.Context, mainPageUrl string) ([]string, error) {
+	urls := make([]string, 0)
+	seenURLs := make(map[string]bool)
+	seenSitemaps := make(map[string]bool)
+	pending := []string{mainPageUrl}
+
+	for len(pending) > 0 {
+		sitemapURL := pending[0]
+		pending = pending[1:]
+
+		if seenSitemaps[sitemapURL] {
+			continue
+		}
+		if len(seenSitemaps) == maxSitemaps {
+			fmt.Printf("[SCRAPER] Stopping after %d sitemaps, %d left unread\n", maxSitemaps, len(pending)+1)
+			break
+		}
+		seenSitemaps[sitemapURL] = true
+
+		document, err := g.fetchSitemap(ctx, sitemapURL)
+		if err != nil {
+			// Only the sitemap we were pointed at is essential, a broken child just loses its pages
+			if sitemapURL == mainPageUrl || ctx.Err() != nil {
+				return nil, err
+			}
+			fmt.Printf("Error reading sitemap %s: %v\n", sitemapURL, err)
+			continue
+		}
+
+		for _, child := range document.Sitemaps {
+			if loc := strings.TrimSpace(child.Loc); loc != "" {
+				pending = append(pending, resolveParkURL(g.baseURL, loc))
+			}
+		}
+
+		for _, entry := range document.URLs {
+			loc := strings.TrimSpace(entry.Loc)
+			if loc == "" || !g.urlPattern.MatchString(loc) {
+				continue
+			}
+
+			pageURL := resolveParkURL(g.baseURL, loc)
+			if seenURLs[pageURL] {
+				continue
+			}
+			seenURLs[pageURL] = true
+			urls = append(urls, pageURL)
+
+			if lastMod, ok := parseLastMod(entry.LastMod); ok {
+				g.lastModifiedMu.Lock()
+				g.lastModified[pageURL] = lastMod
+				g.lastModifiedMu.Unlock()
+			}
+		}
+	}
+
+	g.pagesWalked = len(seenSitemaps)
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no park URLs found in sitemap '%s' matching pattern '%s'", mainPageUrl, g.urlPattern)
+	}
+
+	fmt.Printf("[SCRAPER] Walked %d sitemaps\n", len(seenSitemaps))
+	return urls, nil
+}
+
+// PagesWalked returns how many sitemap files the last GatherUrls call read
+func (g *SitemapParkUrlGatherer) PagesWalked() int {
+	return g.pagesWalked
+}
+
+// LastModified returns the <lastmod> the sitemap gave for pageURL.
+// ok is false when the URL was not gathered or had no valid lastmod.
+func (g *SitemapParkUrlGatherer) LastModified(pageURL string) (time.Time, bool) {
+	g.lastModifiedMu.Lock()
+	defer g.lastModifiedMu.Unlock()
+
+	lastMod, ok := g.lastModified[pageURL]
+	return lastMod, ok
+}
+
+// fetchSitemap downloads and parses one sitemap file, decompressing it if it is gzipped
+func (g *SitemapParkUrlGatherer) fetchSitemap(ctx context.Context, sitemapURL string) (*sitemapDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", sitemapURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", g.userAgent)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sitemap: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	// sitemap.xml.gz files are usually served as application/gzip rather than with
+	// Content-Encoding, so look at the content itself
+	body := bufio.NewReader(resp.Body)
+	var reader io.Reader = body
+	if magic, err := body.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap: %w", err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	var document sitemapDocument
+	if err := xml.NewDecoder(reader).Decode(&document); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap XML: %w", err)
+	}
+
+	return &document, nil
+}
+
+// parseLastMod parses the W3C datetime formats allowed in <lastmod>
+func parseLastMod(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if lastMod, err := time.Parse(layout, value); err == nil {
+			return lastMod, true
+		}
+	}
+	return time.Time{}, false
+}
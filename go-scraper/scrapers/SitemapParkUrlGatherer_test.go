@@ -0,0 +1,178 @@
+package scrapers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// gzipped compresses body the way sitemap.xml.gz files are stored
+func gzipped(t *testing.T, body string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(body)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newSitemapServer serves a gzipped sitemap index pointing at a gzipped sitemap, a plain one
+// and one that is missing
+func newSitemapServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	index := gzipped(t, fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/sitemap-parks.xml.gz</loc></sitemap>
+  <sitemap><loc>/sitemap-news.xml</loc></sitemap>
+  <sitemap><loc>%[1]s/sitemap-missing.xml</loc></sitemap>
+</sitemapindex>`, server.URL))
+	parks := gzipped(t, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>/parks/starved-rock</loc><lastmod>2026-09-30T08:15:00+00:00</lastmod></url>
+  <url><loc>/parks/chain-o-lakes</loc><lastmod>2026-03-01</lastmod></url>
+  <url><loc>/parks/volo-bog</loc><lastmod>sometime</lastmod></url>
+  <url><loc>/about</loc><lastmod>2026-01-01</lastmod></url>
+</urlset>`)
+
+	mux.HandleFunc("/sitemap_index.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Write(index)
+	})
+	mux.HandleFunc("/sitemap-parks.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Write(parks)
+	})
+	mux.HandleFunc("/sitemap-news.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>/news/2026/park-reopens</loc></url>
+  <url><loc>/parks/starved-rock</loc></url>
+  <url><loc>/parks/beall-woods</loc></url>
+</urlset>`)
+	})
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSitemapGathererFollowsGzippedIndex(t *testing.T) {
+	server := newSitemapServer(t)
+	gatherer, err := NewSitemapParkUrlGatherer(server.URL, `/parks/`, server.Client())
+	if err != nil {
+		t.Fatalf("NewSitemapParkUrlGatherer: %v", err)
+	}
+
+	urls, err := gatherer.GatherUrls(context.Background(), server.URL+"/sitemap_index.xml.gz")
+	if err != nil {
+		t.Fatalf("GatherUrls: %v", err)
+	}
+
+	// The missing sitemap only loses its own pages, and pages listed twice are kept once
+	want := []string{
+		server.URL + "/parks/starved-rock",
+		server.URL + "/parks/chain-o-lakes",
+		server.URL + "/parks/volo-bog",
+		server.URL + "/parks/beall-woods",
+	}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("got URLs %v, want %v", urls, want)
+	}
+}
+
+func TestSitemapGathererFiltersByPattern(t *testing.T) {
+	server := newSitemapServer(t)
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{`/parks/(starved|volo)-`, []string{"/parks/starved-rock", "/parks/volo-bog"}},
+		{`/news/`, []string{"/news/2026/park-reopens"}},
+		{``, []string{"/parks/starved-rock", "/parks/chain-o-lakes", "/parks/volo-bog", "/about", "/news/2026/park-reopens", "/parks/beall-woods"}},
+	}
+
+	for _, test := range tests {
+		gatherer, err := NewSitemapParkUrlGatherer(server.URL, test.pattern, server.Client())
+		if err != nil {
+			t.Fatalf("NewSitemapParkUrlGatherer(%q): %v", test.pattern, err)
+		}
+
+		urls, err := gatherer.GatherUrls(context.Background(), server.URL+"/sitemap_index.xml.gz")
+		if err != nil {
+			t.Fatalf("GatherUrls with pattern %q: %v", test.pattern, err)
+		}
+
+		want := make([]string, len(test.want))
+		for i, path := range test.want {
+			want[i] = server.URL + path
+		}
+		if !reflect.DeepEqual(urls, want) {
+			t.Errorf("pattern %q: got URLs %v, want %v", test.pattern, urls, want)
+		}
+	}
+
+	gatherer, err := NewSitemapParkUrlGatherer(server.URL, `/campgrounds/`, server.Client())
+	if err != nil {
+		t.Fatalf("NewSitemapParkUrlGatherer: %v", err)
+	}
+	if _, err := gatherer.GatherUrls(context.Background(), server.URL+"/sitemap_index.xml.gz"); err == nil {
+		t.Error("GatherUrls with a pattern matching nothing succeeded")
+	}
+
+	if _, err := NewSitemapParkUrlGatherer(server.URL, `/parks/(`, server.Client()); err == nil {
+		t.Error("NewSitemapParkUrlGatherer accepted an invalid pattern")
+	}
+}
+
+func TestSitemapLastModifiedPassesThroughMerging(t *testing.T) {
+	server := newSitemapServer(t)
+	sitemap, err := NewSitemapParkUrlGatherer(server.URL, `/parks/`, server.Client())
+	if err != nil {
+		t.Fatalf("NewSitemapParkUrlGatherer: %v", err)
+	}
+	static := NewStaticListParkUrlGatherer([]string{server.URL + "/parks/illinois-beach"})
+	merged := NewMergingParkUrlGatherer(static, sitemap)
+
+	var gatherer ParkUrlGatherer = merged
+	if _, err := gatherer.GatherUrls(context.Background(), server.URL+"/sitemap_index.xml.gz"); err != nil {
+		t.Fatalf("GatherUrls: %v", err)
+	}
+	reporter, ok := gatherer.(LastModifiedReporter)
+	if !ok {
+		t.Fatal("the merging gatherer doesn't report lastmod")
+	}
+
+	tests := []struct {
+		path string
+		want time.Time
+		ok   bool
+	}{
+		{"/parks/starved-rock", time.Date(2026, 9, 30, 8, 15, 0, 0, time.UTC), true},
+		{"/parks/chain-o-lakes", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), true},
+		{"/parks/volo-bog", time.Time{}, false},       // Unparseable lastmod
+		{"/parks/beall-woods", time.Time{}, false},    // No lastmod
+		{"/parks/illinois-beach", time.Time{}, false}, // Static URL
+		{"/about", time.Time{}, false},                // Filtered out
+	}
+	for _, test := range tests {
+		got, ok := reporter.LastModified(server.URL + test.path)
+		if ok != test.ok || !got.Equal(test.want) {
+			t.Errorf("LastModified(%s) = %v, %v, want %v, %v", test.path, got, ok, test.want, test.ok)
+		}
+	}
+}
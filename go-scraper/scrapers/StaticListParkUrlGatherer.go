@@ -0,0 +1,113 @@
+package scrapers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// StaticListParkUrlGatherer implements ParkUrlGatherer with a fixed list of park URLs,
+// such as the "urls" array of a state in urls.json
+type StaticListParkUrlGatherer struct {
+	urls []string
+}
+
+// NewStaticListParkUrlGatherer creates a gatherer that always returns urls, without duplicates
+func NewStaticListParkUrlGatherer(urls []string) *StaticListParkUrlGatherer {
+	return &StaticListParkUrlGatherer{
+		urls: appendUnique(nil, make(map[string]bool), urls),
+	}
+}
+
+// GatherUrls returns the configured URLs. mainPageUrl is not used.
+func (g *StaticListParkUrlGatherer) GatherUrls(ctx context.Context, mainPageUrl string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(g.urls) == 0 {
+		return nil, fmt.Errorf("no static park URLs configured")
+	}
+
+	return append([]string(nil), g.urls...), nil
+}
+
+// MergingParkUrlGatherer implements ParkUrlGatherer by taking the union of several gatherers,
+// e.g. a listing page plus static URLs of parks the listing misses
+type MergingParkUrlGatherer struct {
+	gatherers []ParkUrlGatherer
+}
+
+// NewMergingParkUrlGatherer creates a gatherer that returns the URLs of all gatherers,
+// in the order given and without duplicates
+func NewMergingParkUrlGatherer(gatherers ...ParkUrlGatherer) *MergingParkUrlGatherer {
+	return &MergingParkUrlGatherer{
+		gatherers: gatherers,
+	}
+}
+
+// GatherUrls runs every gatherer against mainPageUrl. A gatherer that fails is logged and
+// skipped so the others still contribute; an error is only returned if all of them fail
+// or ctx is done.
+func (g *MergingParkUrlGatherer) GatherUrls(ctx context.Context, mainPageUrl string) ([]string, error) {
+	urls := make([]string, 0)
+	seen := make(map[string]bool)
+	var errs []error
+
+	for _, gatherer := range g.gatherers {
+		gathered, err := gatherer.GatherUrls(ctx, mainPageUrl)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			fmt.Printf("[SCRAPER] One of the merged URL gatherers failed: %v\n", err)
+			errs = append(errs, err)
+			continue
+		}
+
+		urls = appendUnique(urls, seen, gathered)
+	}
+
+	if len(errs) == len(g.gatherers) {
+		return nil, errors.Join(errs...)
+	}
+
+	return urls, nil
+}
+
+// PagesWalked returns how many listing pages the merged gatherers fetched together
+func (g *MergingParkUrlGatherer) PagesWalked() int {
+	pages := 0
+	for _, gatherer := range g.gatherers {
+		if walker, ok := gatherer.(PageWalker); ok {
+			pages += walker.PagesWalked()
+		}
+	}
+	return pages
+}
+
+// LastModified returns the lastmod of the first merged gatherer that reports one for pageURL
+func (g *MergingParkUrlGatherer) LastModified(pageURL string) (time.Time, bool) {
+	for _, gatherer := range g.gatherers {
+		reporter, ok := gatherer.(LastModifiedReporter)
+		if !ok {
+			continue
+		}
+		if lastModified, ok := reporter.LastModified(pageURL); ok {
+			return lastModified, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// appendUnique appends the URLs not yet in seen to urls, recording them in seen
+func appendUnique(urls []string, seen map[string]bool, more []string) []string {
+	for _, url := range more {
+		if url == "" || seen[url] {
+			continue
+		}
+		seen[url] = true
+		urls = append(urls, url)
+	}
+	return urls
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"scraper/writers"
+)
+
+// seedSQLPath is where the database picks its seed data up from on first start
+var seedSQLPath = filepath.Join("..", "database", "init", "02-seed-data.sql")
+
+// generateSeedSQL implements the seed-sql command. It turns the parks the JSON writer saved
+// under a data directory into seed SQL for the database, without scraping anything.
+func generateSeedSQL(args []string) {
+	flags := flag.NewFlagSet("seed-sql", flag.ExitOnError)
+	dataFlag := flags.String("data", "data", "Directory the JSON writer saved parks to, one subdirectory per state.")
+	outFlag := flags.String("out", seedSQLPath, "File to write the seed SQL to, or - for stdout.")
+	flags.Parse(args)
+
+	loaded, err := writers.LoadParkJSONFiles(*dataFlag)
+	if err != nil {
+		log.Fatalf("Failed to load parks: %v", err)
+	}
+
+	seedWriter := writers.NewSeedSQLWriter()
+	for _, event := range loaded {
+		if err := seedWriter.OnParkScraped(context.Background(), event); err != nil {
+			log.Fatalf("Failed to add park to seed: %v", err)
+		}
+	}
+
+	if *outFlag == "-" {
+		err = seedWriter.WriteSQL(os.Stdout)
+	} else {
+		err = seedWriter.WriteFile(*outFlag)
+	}
+	if err != nil {
+		log.Fatalf("Failed to write seed SQL: %v", err)
+	}
+	if *outFlag != "-" {
+		fmt.Printf("Wrote %d parks to %s\n", seedWriter.Len(), *outFlag)
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,6 +14,7 @@ type GeocodingService struct {
 	apiKey     string
 	httpClient *http.Client
 	baseURL    string
+	onFailure  func(ctx context.Context, address string, err error)
 }
 
 // Coordinates represents a geographic location
@@ -50,17 +52,38 @@ type MapBoxResponse struct {
 
 // NewGeocodingService creates a new geocoding service instance
 func NewGeocodingService(apiKey string) *GeocodingService {
+	return NewGeocodingServiceWithClient(apiKey, &http.Client{
+		Timeout: 10 * time.Second,
+	})
+}
+
+// NewGeocodingServiceWithClient creates a geocoding service that sends its requests through
+// httpClient, e.g. one that records or replays fixtures
+func NewGeocodingServiceWithClient(apiKey string, httpClient *http.Client) *GeocodingService {
 	return &GeocodingService{
-		apiKey: apiKey,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		baseURL: "https://api.mapbox.com/geocoding/v5/mapbox.places",
+		apiKey:     apiKey,
+		httpClient: httpClient,
+		baseURL:    "https://api.mapbox.com/geocoding/v5/mapbox.places",
+	}
+}
+
+// OnGeocodeFailed registers a callback for addresses that could not be geocoded. It gets the ctx
+// passed to GeocodeAddress, is not called when that ctx is done, and must be safe for concurrent use.
+func (g *GeocodingService) OnGeocodeFailed(f func(ctx context.Context, address string, err error)) {
+	g.onFailure = f
+}
+
+// GeocodeAddress converts an address string to latitude and longitude coordinates.
+// The request is abandoned once ctx is done.
+func (g *GeocodingService) GeocodeAddress(ctx context.Context, address string) (*Coordinates, error) {
+	coords, err := g.geocode(ctx, address)
+	if err != nil && g.onFailure != nil && ctx.Err() == nil {
+		g.onFailure(ctx, address, err)
 	}
+	return coords, err
 }
 
-// GeocodeAddress converts an address string to latitude and longitude coordinates
-func (g *GeocodingService) GeocodeAddress(address string) (*Coordinates, error) {
+func (g *GeocodingService) geocode(ctx context.Context, address string) (*Coordinates, error) {
 	if address == "" {
 		return nil, fmt.Errorf("address cannot be empty")
 	}
@@ -77,7 +100,12 @@ func (g *GeocodingService) GeocodeAddress(address string) (*Coordinates, error)
 		g.baseURL, encodedAddress, g.apiKey)
 
 	// Make the HTTP request
-	resp, err := g.httpClient.Get(requestURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create geocoding request: %w", err)
+	}
+
+	resp, err := g.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make geocoding request: %w", err)
 	}
@@ -0,0 +1,265 @@
+package transport
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrNotCached is returned in offline mode for requests the cache can't answer
+var ErrNotCached = errors.New("not in HTTP cache (offline mode)")
+
+// CacheTransport is an http.RoundTripper that stores GET responses on disk, keyed by URL.
+// Entries younger than maxAge are served without touching the network. Older entries are
+// revalidated with If-None-Match / If-Modified-Since and reused when the server answers 304.
+// In offline mode every request is answered from the cache, whatever its age, or fails with ErrNotCached.
+type CacheTransport struct {
+	dir     string
+	maxAge  time.Duration
+	offline bool
+	next    http.RoundTripper
+	now     func() time.Time
+}
+
+// cacheEntry is the metadata stored next to a cached body
+type cacheEntry struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	StoredAt   time.Time   `json:"storedAt"`
+	BodySHA256 string      `json:"bodySha256,omitempty"` // Empty in entries stored before it was recorded
+}
+
+// NewCacheTransport wraps next with an on-disk cache in dir. A nil next uses http.DefaultTransport.
+func NewCacheTransport(dir string, maxAge time.Duration, offline bool, next http.RoundTripper) (*CacheTransport, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %s: %w", dir, err)
+	}
+
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &CacheTransport{
+		dir:     dir,
+		maxAge:  maxAge,
+		offline: offline,
+		next:    next,
+		now:     time.Now,
+	}, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		if t.offline {
+			return nil, ErrNotCached
+		}
+		return t.next.RoundTrip(req)
+	}
+
+	key := cacheKey(req.URL.String())
+	entry, body, err := t.load(key)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("[CACHE] Ignoring unreadable entry for %s: %v", req.URL, err)
+		entry = nil
+	}
+
+	if entry != nil && (t.offline || t.now().Sub(entry.StoredAt) < t.maxAge) {
+		return entry.response(req, body, "HIT"), nil
+	}
+	if t.offline {
+		return nil, ErrNotCached
+	}
+
+	// Stale or missing: ask the server, conditionally if we have validators
+	outReq := req
+	if entry != nil {
+		outReq = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			outReq.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+			outReq.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(outReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		entry.StoredAt = t.now()
+		if err := t.store(key, entry, body); err != nil {
+			log.Printf("[CACHE] Failed to refresh entry for %s: %v", req.URL, err)
+		}
+		return entry.response(req, body, "REVALIDATED"), nil
+	}
+
+	if !cacheable(req, resp.StatusCode) {
+		return resp, nil
+	}
+
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	entry = &cacheEntry{
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		StoredAt:   t.now(),
+	}
+	if err := t.store(key, entry, body); err != nil {
+		log.Printf("[CACHE] Failed to store %s: %v", req.URL, err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
+
+// cacheable reports whether a response is stored. Besides 200s that is a robots.txt answered with
+// a 4xx, which means the host has no restrictions: without it an offline run would fail every
+// request to the host in RobotsTransport.
+func cacheable(req *http.Request, statusCode int) bool {
+	if statusCode == http.StatusOK {
+		return true
+	}
+	return req.URL.Path == "/robots.txt" && statusCode >= 400 && statusCode < 500
+}
+
+// cacheKey turns a URL into a file name
+func cacheKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// load reads the entry and body stored under key
+func (t *CacheTransport) load(key string) (*cacheEntry, []byte, error) {
+	metaPath := filepath.Join(t.dir, key+".json")
+	meta, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(meta, &entry); err != nil {
+		return nil, nil, err
+	}
+
+	body, err := os.ReadFile(filepath.Join(t.dir, key+".body"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if entry.BodySHA256 != "" && entry.BodySHA256 != bodyHash(body) {
+		return nil, nil, errors.New("body doesn't match its entry")
+	}
+
+	return &entry, body, nil
+}
+
+// store writes entry and body under key. Both are written to temp files before either is renamed
+// into place, so concurrent readers never see a half-written file. The entry records a hash of
+// the body, so a crash between the two renames leaves a pair load rejects.
+func (t *CacheTransport) store(key string, entry *cacheEntry, body []byte) error {
+	entry.BodySHA256 = bodyHash(body)
+	meta, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	bodyPath := filepath.Join(t.dir, key+".body")
+	metaPath := filepath.Join(t.dir, key+".json")
+	bodyTmp, err := writeTempFile(bodyPath, body)
+	if err != nil {
+		return err
+	}
+	metaTmp, err := writeTempFile(metaPath, meta)
+	if err != nil {
+		os.Remove(bodyTmp)
+		return err
+	}
+
+	if err := os.Rename(bodyTmp, bodyPath); err != nil {
+		os.Remove(bodyTmp)
+		os.Remove(metaTmp)
+		return err
+	}
+	if err := os.Rename(metaTmp, metaPath); err != nil {
+		os.Remove(metaTmp)
+		return err
+	}
+	return nil
+}
+
+// bodyHash is the hex SHA-256 of a cached body
+func bodyHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeFileAtomic writes data to a temp file next to path and renames it into place
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := writeTempFile(path, data)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// writeTempFile writes data to a new temp file next to path and returns its name
+func writeTempFile(path string, data []byte) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
+
+// response builds an http.Response for req from a cached entry
+func (e *cacheEntry) response(req *http.Request, body []byte, cacheStatus string) *http.Response {
+	header := e.Header.Clone()
+	header.Set("X-Cache", cacheStatus)
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,244 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// cachedResponse is what a test read from a response
+type cachedResponse struct {
+	StatusCode int
+	Body       string
+	Cache      string // The X-Cache header, empty if the response came from the server
+}
+
+// get sends a GET for rawURL through rt
+func get(t *testing.T, rt http.RoundTripper, rawURL string) (cachedResponse, error) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return cachedResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	return cachedResponse{resp.StatusCode, string(body), resp.Header.Get("X-Cache")}, nil
+}
+
+// mustGet is get for requests that must not fail
+func mustGet(t *testing.T, rt http.RoundTripper, rawURL string) cachedResponse {
+	t.Helper()
+
+	resp, err := get(t, rt, rawURL)
+	if err != nil {
+		t.Fatalf("GET %s: %v", rawURL, err)
+	}
+	return resp
+}
+
+// requestLog records the requests a test server received
+type requestLog struct {
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func (l *requestLog) add(r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests = append(l.requests, r)
+}
+
+func (l *requestLog) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.requests)
+}
+
+func (l *requestLog) last() *http.Request {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.requests[len(l.requests)-1]
+}
+
+// newTestCache creates a cache in dir whose clock only moves when the returned function is called
+func newTestCache(t *testing.T, dir string, maxAge time.Duration, offline bool) (*CacheTransport, func(time.Duration)) {
+	t.Helper()
+
+	cache, err := NewCacheTransport(dir, maxAge, offline, nil)
+	if err != nil {
+		t.Fatalf("NewCacheTransport: %v", err)
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	return cache, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCacheServesFreshEntriesAndRefetchesExpiredOnes(t *testing.T) {
+	var log requestLog
+	body := "first"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		io.WriteString(w, body)
+	}))
+	defer server.Close()
+
+	cache, advance := newTestCache(t, t.TempDir(), time.Hour, false)
+
+	if got := mustGet(t, cache, server.URL+"/park"); got != (cachedResponse{200, "first", ""}) {
+		t.Errorf("got %+v from the server, want the first body", got)
+	}
+	advance(time.Hour - time.Second)
+	if got := mustGet(t, cache, server.URL+"/park"); got != (cachedResponse{200, "first", "HIT"}) {
+		t.Errorf("got %+v before max-age, want a cache hit", got)
+	}
+	if count := log.count(); count != 1 {
+		t.Errorf("the server got %d requests, want only the first", count)
+	}
+
+	// Without validators an expired entry is fetched again and replaced
+	body = "second"
+	advance(time.Second)
+	if got := mustGet(t, cache, server.URL+"/park"); got != (cachedResponse{200, "second", ""}) {
+		t.Errorf("got %+v after max-age, want the new body from the server", got)
+	}
+	if got := mustGet(t, cache, server.URL+"/park"); got != (cachedResponse{200, "second", "HIT"}) {
+		t.Errorf("got %+v, want the new body cached", got)
+	}
+}
+
+func TestCacheRevalidatesExpiredEntries(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Sun, 01 Jun 2025 11:00:00 GMT"
+
+	var log requestLog
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		io.WriteString(w, "park page")
+	}))
+	defer server.Close()
+
+	cache, advance := newTestCache(t, t.TempDir(), time.Hour, false)
+	mustGet(t, cache, server.URL+"/park")
+
+	advance(2 * time.Hour)
+	if got := mustGet(t, cache, server.URL+"/park"); got != (cachedResponse{200, "park page", "REVALIDATED"}) {
+		t.Errorf("got %+v, want the cached body after a 304", got)
+	}
+	if req := log.last(); req.Header.Get("If-None-Match") != etag || req.Header.Get("If-Modified-Since") != lastModified {
+		t.Errorf("revalidated with If-None-Match %q and If-Modified-Since %q, want the stored validators",
+			req.Header.Get("If-None-Match"), req.Header.Get("If-Modified-Since"))
+	}
+
+	// The 304 makes the entry fresh again
+	advance(time.Hour - time.Second)
+	if got := mustGet(t, cache, server.URL+"/park"); got.Cache != "HIT" {
+		t.Errorf("got %+v after revalidating, want a cache hit", got)
+	}
+	if count := log.count(); count != 2 {
+		t.Errorf("the server got %d requests, want 2", count)
+	}
+}
+
+func TestOfflineCacheHitsAndMisses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/park":
+			io.WriteString(w, "park page")
+		case "/robots.txt":
+			http.NotFound(w, r)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	dir := t.TempDir()
+	online, _ := newTestCache(t, dir, time.Hour, false)
+	mustGet(t, online, server.URL+"/park")
+	mustGet(t, online, server.URL+"/robots.txt")
+	mustGet(t, online, server.URL+"/broken")
+	server.Close()
+
+	// Offline, entries are served whatever their age and the closed server is never asked
+	offline, advance := newTestCache(t, dir, time.Hour, true)
+	advance(24 * time.Hour)
+	if got := mustGet(t, offline, server.URL+"/park"); got != (cachedResponse{200, "park page", "HIT"}) {
+		t.Errorf("got %+v, want the cached page", got)
+	}
+	if got := mustGet(t, offline, server.URL+"/robots.txt"); got != (cachedResponse{404, "404 page not found\n", "HIT"}) {
+		t.Errorf("got %+v, want the cached robots.txt 404", got)
+	}
+
+	for _, path := range []string{"/never-fetched", "/broken"} {
+		if _, err := get(t, offline, server.URL+path); !errors.Is(err, ErrNotCached) {
+			t.Errorf("GET %s = %v, want %v", path, err, ErrNotCached)
+		}
+	}
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/park", nil)
+	if _, err := offline.RoundTrip(req); !errors.Is(err, ErrNotCached) {
+		t.Errorf("POST = %v, want %v", err, ErrNotCached)
+	}
+}
+
+func TestOfflineRobotsCheckUsesCachedNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, "park page")
+	}))
+	dir := t.TempDir()
+	online, _ := newTestCache(t, dir, time.Hour, false)
+	mustGet(t, NewRobotsTransport("test-agent", nil, online), server.URL+"/park")
+	server.Close()
+
+	offline, _ := newTestCache(t, dir, time.Hour, true)
+	if got := mustGet(t, NewRobotsTransport("test-agent", nil, offline), server.URL+"/park"); got.Body != "park page" {
+		t.Errorf("got %+v offline, want the cached page", got)
+	}
+}
+
+func TestCacheIgnoresBodyThatDoesNotMatchEntry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "park page")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	online, _ := newTestCache(t, dir, time.Hour, false)
+	mustGet(t, online, server.URL+"/park")
+
+	// As if a crash had replaced the body but not its entry
+	bodyFile := filepath.Join(dir, cacheKey(server.URL+"/park")+".body")
+	if err := os.WriteFile(bodyFile, []byte("another page"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	offline, _ := newTestCache(t, dir, time.Hour, true)
+	if got, err := get(t, offline, server.URL+"/park"); !errors.Is(err, ErrNotCached) {
+		t.Errorf("got %+v, %v, want the entry treated as missing", got, err)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(dir, "*.tmp*")); len(temps) != 0 {
+		t.Errorf("left temp files %v behind", temps)
+	}
+}
@@ -0,0 +1,31 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+)
+
+// contextTransport attaches a fixed context to every request it sends
+type contextTransport struct {
+	ctx  context.Context
+	next http.RoundTripper
+}
+
+// WithContext wraps next so every request runs under ctx. It is meant for clients such as
+// colly collectors, which build their own requests and have no way to accept a context.
+// A nil next uses http.DefaultTransport.
+func WithContext(ctx context.Context, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &contextTransport{
+		ctx:  ctx,
+		next: next,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.next.RoundTrip(req.WithContext(t.ctx))
+}
@@ -0,0 +1,198 @@
+package transport
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrNoFixture is returned by ReplayTransport for requests that were never recorded
+var ErrNoFixture = errors.New("no recorded fixture")
+
+// redactedParams are query parameters that carry secrets. They are masked in recorded
+// fixtures and ignored when matching requests on replay.
+var redactedParams = []string{"access_token", "api_key", "apikey", "key", "token"}
+
+// fixture is the metadata stored next to a recorded body
+type fixture struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+}
+
+// RecordingTransport is an http.RoundTripper that saves every response it sees under dir,
+// so a real run can be turned into fixtures for ReplayTransport
+type RecordingTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+// NewRecordingTransport wraps next and records its responses under dir.
+// A nil next uses http.DefaultTransport.
+func NewRecordingTransport(dir string, next http.RoundTripper) *RecordingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &RecordingTransport{
+		dir:  dir,
+		next: next,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+
+	f := &fixture{
+		Method:     req.Method,
+		URL:        redactURL(req.URL),
+		StatusCode: resp.StatusCode,
+		Header:     header,
+	}
+	if err := saveFixture(t.dir, f, body); err != nil {
+		return nil, fmt.Errorf("failed to record fixture for %s: %w", f.URL, err)
+	}
+
+	return resp, nil
+}
+
+// ReplayTransport is an http.RoundTripper that answers requests from fixtures recorded by
+// RecordingTransport and never touches the network
+type ReplayTransport struct {
+	dir string
+}
+
+// NewReplayTransport creates a ReplayTransport serving the fixtures under dir
+func NewReplayTransport(dir string) *ReplayTransport {
+	return &ReplayTransport{dir: dir}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := redactURL(req.URL)
+
+	f, body, err := loadFixture(t.dir, req.Method, key)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, key, ErrNoFixture)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fixture for %s: %w", key, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.StatusCode, http.StatusText(f.StatusCode)),
+		StatusCode:    f.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// redactURL returns u as a string with secret query parameters masked
+func redactURL(u *url.URL) string {
+	redacted := *u
+	query := redacted.Query()
+	for _, param := range redactedParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+		}
+	}
+	redacted.RawQuery = query.Encode()
+
+	return redacted.String()
+}
+
+var unsafeFixtureChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// fixturePath returns the path, without extension, of the fixture for method and rawURL.
+// Fixtures are grouped per host and named after the URL path so they are easy to find
+// in testdata; a hash of the full request keeps names unique.
+func fixturePath(dir string, method string, rawURL string) string {
+	sum := sha256.Sum256([]byte(method + " " + rawURL))
+	hash := hex.EncodeToString(sum[:])[:12]
+
+	host := "unknown-host"
+	name := "root"
+	if parsed, err := url.Parse(rawURL); err == nil {
+		if parsed.Host != "" {
+			host = unsafeFixtureChars.ReplaceAllString(parsed.Host, "_")
+		}
+		if path := strings.Trim(unsafeFixtureChars.ReplaceAllString(parsed.Path, "_"), "_"); path != "" {
+			name = path
+		}
+	}
+	if len(name) > 80 {
+		name = name[len(name)-80:]
+	}
+
+	return filepath.Join(dir, host, fmt.Sprintf("%s-%s-%s", strings.ToLower(method), name, hash))
+}
+
+// saveFixture writes f and body to dir
+func saveFixture(dir string, f *fixture, body []byte) error {
+	path := fixturePath(dir, f.Method, f.URL)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	meta, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(path+".body", body); err != nil {
+		return err
+	}
+	return writeFileAtomic(path+".json", meta)
+}
+
+// loadFixture reads the fixture recorded for method and rawURL from dir
+func loadFixture(dir string, method string, rawURL string) (*fixture, []byte, error) {
+	path := fixturePath(dir, method, rawURL)
+
+	meta, err := os.ReadFile(path + ".json")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var f fixture
+	if err := json.Unmarshal(meta, &f); err != nil {
+		return nil, nil, err
+	}
+
+	body, err := os.ReadFile(path + ".body")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &f, body, nil
+}
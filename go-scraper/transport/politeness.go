@@ -0,0 +1,187 @@
+package transport
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PolitenessPolicy controls how often we are allowed to send requests to a single host
+type PolitenessPolicy struct {
+	RequestsPerSecond float64       // Token bucket refill rate, 0 disables the bucket
+	Burst             int           // Token bucket size
+	MinDelay          time.Duration // Minimum gap between the start of two requests
+	Jitter            time.Duration // Random extra delay added on top of MinDelay
+}
+
+// DefaultPolitenessPolicy is used for hosts without an explicit policy
+var DefaultPolitenessPolicy = PolitenessPolicy{
+	RequestsPerSecond: 2,
+	Burst:             2,
+	MinDelay:          100 * time.Millisecond,
+	Jitter:            200 * time.Millisecond,
+}
+
+// HostLimiter throttles outbound requests per host. It is safe for concurrent use and
+// is meant to be shared by every gatherer and scraper in a run.
+type HostLimiter struct {
+	mu            sync.Mutex
+	defaultPolicy PolitenessPolicy
+	policies      map[string]PolitenessPolicy
+	buckets       map[string]*hostBucket
+}
+
+// hostBucket is the throttling state of a single host
+type hostBucket struct {
+	policy  PolitenessPolicy
+	tokens  float64
+	updated time.Time // When tokens was last refilled
+	next    time.Time // Earliest start of the next request
+}
+
+// NewHostLimiter creates a HostLimiter that applies defaultPolicy to unknown hosts
+func NewHostLimiter(defaultPolicy PolitenessPolicy) *HostLimiter {
+	return &HostLimiter{
+		defaultPolicy: defaultPolicy,
+		policies:      make(map[string]PolitenessPolicy),
+		buckets:       make(map[string]*hostBucket),
+	}
+}
+
+// SetPolicy overrides the policy for a host (e.g. "dnr.illinois.gov")
+func (l *HostLimiter) SetPolicy(host string, policy PolitenessPolicy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	host = strings.ToLower(host)
+	l.policies[host] = policy
+	if bucket, ok := l.buckets[host]; ok {
+		bucket.policy = policy
+	}
+}
+
+// EnsureMinDelay raises the minimum delay for host to at least delay, e.g. to honour a robots.txt Crawl-delay
+func (l *HostLimiter) EnsureMinDelay(host string, delay time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	host = strings.ToLower(host)
+	policy, ok := l.policies[host]
+	if !ok {
+		policy = l.defaultPolicy
+	}
+	if policy.MinDelay >= delay {
+		return
+	}
+
+	policy.MinDelay = delay
+	l.policies[host] = policy
+	if bucket, ok := l.buckets[host]; ok {
+		bucket.policy = policy
+	}
+}
+
+// Wait blocks until a request to host may be sent, or ctx is done
+func (l *HostLimiter) Wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+	now := time.Now()
+	start := l.bucket(strings.ToLower(host), now).reserve(now)
+	l.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// bucket returns the state for host, creating it on first use. Callers must hold l.mu.
+func (l *HostLimiter) bucket(host string, now time.Time) *hostBucket {
+	if bucket, ok := l.buckets[host]; ok {
+		return bucket
+	}
+
+	policy, ok := l.policies[host]
+	if !ok {
+		policy = l.defaultPolicy
+	}
+
+	bucket := &hostBucket{
+		policy:  policy,
+		tokens:  float64(max(policy.Burst, 1)),
+		updated: now,
+	}
+	l.buckets[host] = bucket
+	return bucket
+}
+
+// reserve claims the next request slot and returns the time it may start
+func (b *hostBucket) reserve(now time.Time) time.Time {
+	start := now
+	if b.next.After(start) {
+		start = b.next
+	}
+
+	if rate := b.policy.RequestsPerSecond; rate > 0 {
+		burst := float64(max(b.policy.Burst, 1))
+		b.tokens = math.Min(burst, b.tokens+start.Sub(b.updated).Seconds()*rate)
+		b.updated = start
+
+		// Not enough tokens yet: push the start back until one has refilled
+		if b.tokens < 1 {
+			start = start.Add(time.Duration((1 - b.tokens) / rate * float64(time.Second)))
+			b.tokens = 1
+			b.updated = start
+		}
+		b.tokens--
+	}
+
+	var jitter time.Duration
+	if b.policy.Jitter > 0 {
+		jitter = rand.N(b.policy.Jitter)
+	}
+	b.next = start.Add(b.policy.MinDelay + jitter)
+
+	return start
+}
+
+// PoliteTransport is an http.RoundTripper that waits on a HostLimiter before every request
+type PoliteTransport struct {
+	limiter *HostLimiter
+	next    http.RoundTripper
+}
+
+// NewPoliteTransport wraps next so every request is throttled by limiter.
+// A nil next uses http.DefaultTransport.
+func NewPoliteTransport(limiter *HostLimiter, next http.RoundTripper) *PoliteTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &PoliteTransport{
+		limiter: limiter,
+		next:    next,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *PoliteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context(), req.URL.Host); err != nil {
+		return nil, err
+	}
+
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,146 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// reserveAt claims the next request slot for host as if it were now, and returns how long after
+// now that request may start
+func reserveAt(l *HostLimiter, host string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.bucket(strings.ToLower(host), now).reserve(now).Sub(now)
+}
+
+var limiterEpoch = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestHostLimiterRateAndBurst(t *testing.T) {
+	limiter := NewHostLimiter(PolitenessPolicy{RequestsPerSecond: 10, Burst: 3})
+
+	// The burst goes out at once, then one request every 100ms
+	want := []time.Duration{0, 0, 0, 100 * time.Millisecond, 200 * time.Millisecond}
+	for i, wantDelay := range want {
+		if delay := reserveAt(limiter, "dnr.illinois.gov", limiterEpoch); delay != wantDelay {
+			t.Errorf("request %d may start after %v, want %v", i, delay, wantDelay)
+		}
+	}
+
+	// A second later the bucket has refilled to the burst, but no further
+	later := limiterEpoch.Add(time.Second)
+	for i, wantDelay := range want {
+		if delay := reserveAt(limiter, "dnr.illinois.gov", later); delay != wantDelay {
+			t.Errorf("request %d after the refill may start after %v, want %v", i, delay, wantDelay)
+		}
+	}
+}
+
+func TestHostLimiterMinDelayAndJitter(t *testing.T) {
+	const minDelay = 100 * time.Millisecond
+	const jitter = 50 * time.Millisecond
+	limiter := NewHostLimiter(PolitenessPolicy{MinDelay: minDelay, Jitter: jitter})
+
+	previous := reserveAt(limiter, "www.in.gov", limiterEpoch)
+	if previous != 0 {
+		t.Fatalf("the first request may start after %v, want at once", previous)
+	}
+	for i := 1; i < 50; i++ {
+		delay := reserveAt(limiter, "www.in.gov", limiterEpoch)
+		if gap := delay - previous; gap < minDelay || gap >= minDelay+jitter {
+			t.Fatalf("request %d starts %v after the one before, want between %v and %v", i, gap, minDelay, minDelay+jitter)
+		}
+		previous = delay
+	}
+}
+
+func TestHostLimiterIsolatesHosts(t *testing.T) {
+	limiter := NewHostLimiter(PolitenessPolicy{MinDelay: time.Second})
+	limiter.SetPolicy("slow.example.com", PolitenessPolicy{MinDelay: time.Minute})
+
+	reserveAt(limiter, "slow.example.com", limiterEpoch)
+	reserveAt(limiter, "fast.example.com", limiterEpoch)
+
+	tests := []struct {
+		host string
+		want time.Duration
+	}{
+		{"SLOW.example.com", time.Minute},
+		{"fast.example.com", time.Second},
+		{"other.example.com", 0},
+	}
+	for _, test := range tests {
+		if delay := reserveAt(limiter, test.host, limiterEpoch); delay != test.want {
+			t.Errorf("the next request to %s may start after %v, want %v", test.host, delay, test.want)
+		}
+	}
+}
+
+func TestHostLimiterEnsureMinDelayOnlyRaises(t *testing.T) {
+	limiter := NewHostLimiter(PolitenessPolicy{MinDelay: time.Second})
+	limiter.EnsureMinDelay("raised.example.com", 5*time.Second)
+	limiter.EnsureMinDelay("kept.example.com", 500*time.Millisecond)
+
+	for host, want := range map[string]time.Duration{"raised.example.com": 5 * time.Second, "kept.example.com": time.Second} {
+		reserveAt(limiter, host, limiterEpoch)
+		if delay := reserveAt(limiter, host, limiterEpoch); delay != want {
+			t.Errorf("%s: the second request may start after %v, want %v", host, delay, want)
+		}
+	}
+}
+
+func TestPoliteTransportSpacesRequests(t *testing.T) {
+	const minDelay = 50 * time.Millisecond
+
+	var mu sync.Mutex
+	var received []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, time.Now())
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	limiter := NewHostLimiter(PolitenessPolicy{MinDelay: minDelay})
+	client := &http.Client{Transport: NewPoliteTransport(limiter, nil)}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Errorf("GET: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 1; i < len(received); i++ {
+		// Connecting delays a request after the limiter let it go, so allow some slack
+		if gap := received[i].Sub(received[i-1]); gap < minDelay-20*time.Millisecond {
+			t.Errorf("request %d came %v after the one before, want about %v", i, gap, minDelay)
+		}
+	}
+
+	// A request that would have to wait gives up with its context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx, "127.0.0.1"); err != nil {
+		t.Errorf("Wait on a fresh host = %v, want no wait", err)
+	}
+	if err := limiter.Wait(ctx, "127.0.0.1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,126 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/temoto/robotstxt"
+)
+
+// ErrDisallowedByRobots is returned for requests the target host's robots.txt does not allow
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// RobotsTransport is an http.RoundTripper that enforces robots.txt for userAgent.
+// robots.txt is fetched once per host through next and cached for the lifetime of the transport.
+// A Crawl-delay for our user agent is applied to the shared HostLimiter.
+type RobotsTransport struct {
+	userAgent string
+	limiter   *HostLimiter
+	next      http.RoundTripper
+
+	mu    sync.Mutex
+	hosts map[string]*robotsEntry
+}
+
+// robotsEntry holds the parsed robots.txt of a single host
+type robotsEntry struct {
+	once sync.Once
+	data *robotstxt.RobotsData
+	err  error
+}
+
+// NewRobotsTransport wraps next so every request is checked against the host's robots.txt.
+// limiter may be nil if Crawl-delay should be ignored. A nil next uses http.DefaultTransport.
+func NewRobotsTransport(userAgent string, limiter *HostLimiter, next http.RoundTripper) *RobotsTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &RobotsTransport{
+		userAgent: userAgent,
+		limiter:   limiter,
+		next:      next,
+		hosts:     make(map[string]*robotsEntry),
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RobotsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == "/robots.txt" {
+		return t.next.RoundTrip(req)
+	}
+
+	robots, err := t.robotsFor(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch robots.txt for %s: %w", req.URL.Host, err)
+	}
+
+	if !robots.TestAgent(req.URL.EscapedPath(), t.userAgent) {
+		return nil, ErrDisallowedByRobots
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// robotsFor returns the robots.txt rules for the request's host, fetching them on first use
+func (t *RobotsTransport) robotsFor(req *http.Request) (*robotstxt.RobotsData, error) {
+	host := strings.ToLower(req.URL.Host)
+
+	t.mu.Lock()
+	entry, ok := t.hosts[host]
+	if !ok {
+		entry = &robotsEntry{}
+		t.hosts[host] = entry
+	}
+	t.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.data, entry.err = t.fetchRobots(req)
+	})
+
+	// Don't cache network failures, the next request to this host tries again
+	if entry.err != nil {
+		t.mu.Lock()
+		if t.hosts[host] == entry {
+			delete(t.hosts, host)
+		}
+		t.mu.Unlock()
+	}
+
+	return entry.data, entry.err
+}
+
+// fetchRobots downloads and parses robots.txt for the request's host and applies its Crawl-delay
+func (t *RobotsTransport) fetchRobots(req *http.Request) (*robotstxt.RobotsData, error) {
+	robotsURL := url.URL{Scheme: req.URL.Scheme, Host: req.URL.Host, Path: "/robots.txt"}
+
+	robotsReq, err := http.NewRequestWithContext(req.Context(), http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	robotsReq.Header.Set("User-Agent", t.userAgent)
+
+	resp, err := t.next.RoundTrip(robotsReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// 4xx means "no restrictions" and 5xx means "disallow everything", as robotstxt implements
+	robots, err := robotstxt.FromResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if delay := robots.FindGroup(t.userAgent).CrawlDelay; delay > 0 && t.limiter != nil {
+		log.Printf("[ROBOTS] %s asks for a Crawl-delay of %v", req.URL.Host, delay)
+		t.limiter.EnsureMinDelay(req.URL.Host, delay)
+	}
+
+	return robots, nil
+}
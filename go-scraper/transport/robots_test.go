@@ -0,0 +1,184 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// robotsSite serves a robots.txt and counts how often it was fetched. Every other path is a page.
+type robotsSite struct {
+	status int // Of robots.txt, 200 if zero
+	robots string
+
+	mu      sync.Mutex
+	fetches int
+}
+
+func (s *robotsSite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/robots.txt" {
+		io.WriteString(w, "park page")
+		return
+	}
+
+	s.mu.Lock()
+	s.fetches++
+	s.mu.Unlock()
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+	}
+	io.WriteString(w, s.robots)
+}
+
+func (s *robotsSite) robotsFetches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+func TestRobotsTransportAppliesRules(t *testing.T) {
+	site := &robotsSite{robots: "User-agent: test-agent\nDisallow: /private\n\nUser-agent: *\nDisallow: /\n"}
+	server := httptest.NewServer(site)
+	defer server.Close()
+
+	robots := NewRobotsTransport("test-agent", nil, nil)
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{"/parks/starved-rock", true},
+		{"/private", false},
+		{"/private/reports", false},
+		{"/robots.txt", true},
+	}
+	for _, test := range tests {
+		_, err := get(t, robots, server.URL+test.path)
+		if test.allowed && err != nil {
+			t.Errorf("GET %s = %v, want it allowed", test.path, err)
+		}
+		if !test.allowed && !errors.Is(err, ErrDisallowedByRobots) {
+			t.Errorf("GET %s = %v, want %v", test.path, err, ErrDisallowedByRobots)
+		}
+	}
+}
+
+func TestRobotsTransportStatusCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		allowed bool
+	}{
+		{"not found allows everything", http.StatusNotFound, true},
+		{"forbidden allows everything", http.StatusForbidden, true},
+		{"server error disallows everything", http.StatusInternalServerError, false},
+		{"unavailable disallows everything", http.StatusServiceUnavailable, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The body would disallow everything if it were read as rules
+			server := httptest.NewServer(&robotsSite{status: test.status, robots: "User-agent: *\nDisallow: /\n"})
+			defer server.Close()
+
+			_, err := get(t, NewRobotsTransport("test-agent", nil, nil), server.URL+"/parks/starved-rock")
+			if test.allowed && err != nil {
+				t.Errorf("got %v, want the page allowed", err)
+			}
+			if !test.allowed && !errors.Is(err, ErrDisallowedByRobots) {
+				t.Errorf("got %v, want %v", err, ErrDisallowedByRobots)
+			}
+		})
+	}
+}
+
+func TestRobotsTransportAppliesCrawlDelay(t *testing.T) {
+	server := httptest.NewServer(&robotsSite{robots: "User-agent: test-agent\nCrawl-delay: 5\n"})
+	defer server.Close()
+
+	limiter := NewHostLimiter(PolitenessPolicy{MinDelay: time.Second})
+	mustGet(t, NewRobotsTransport("test-agent", limiter, nil), server.URL+"/parks/starved-rock")
+
+	host := mustParseURL(t, server.URL).Host
+	reserveAt(limiter, host, limiterEpoch)
+	if delay := reserveAt(limiter, host, limiterEpoch); delay != 5*time.Second {
+		t.Errorf("the next request to %s may start after %v, want the 5s Crawl-delay", host, delay)
+	}
+}
+
+func TestRobotsTransportFetchesOncePerHost(t *testing.T) {
+	first := &robotsSite{robots: "User-agent: *\nDisallow: /private\n"}
+	second := &robotsSite{robots: "User-agent: *\nDisallow: /private\n"}
+	firstServer := httptest.NewServer(first)
+	defer firstServer.Close()
+	secondServer := httptest.NewServer(second)
+	defer secondServer.Close()
+
+	robots := NewRobotsTransport("test-agent", nil, nil)
+	var wg sync.WaitGroup
+	for i := range 10 {
+		for _, server := range []*httptest.Server{firstServer, secondServer} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				path := "/parks/starved-rock"
+				if i%2 == 1 {
+					path = "/private"
+				}
+				if _, err := get(t, robots, server.URL+path); err != nil && !errors.Is(err, ErrDisallowedByRobots) {
+					t.Errorf("GET %s: %v", path, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	if first.robotsFetches() != 1 || second.robotsFetches() != 1 {
+		t.Errorf("robots.txt was fetched %d and %d times, want once per host", first.robotsFetches(), second.robotsFetches())
+	}
+}
+
+// failingOnce fails the first request it is given and sends the others on to http.DefaultTransport
+type failingOnce struct {
+	failed bool
+}
+
+func (f *failingOnce) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !f.failed {
+		f.failed = true
+		return nil, errors.New("connection refused")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestRobotsTransportRetriesFailedFetch(t *testing.T) {
+	site := &robotsSite{robots: "User-agent: *\nAllow: /\n"}
+	server := httptest.NewServer(site)
+	defer server.Close()
+
+	// A fetch that fails isn't remembered, the next request to the host tries again
+	robots := NewRobotsTransport("test-agent", nil, &failingOnce{})
+	if _, err := get(t, robots, server.URL+"/parks/starved-rock"); err == nil || errors.Is(err, ErrDisallowedByRobots) {
+		t.Fatalf("got %v, want the fetch error", err)
+	}
+	if _, err := get(t, robots, server.URL+"/parks/starved-rock"); err != nil {
+		t.Errorf("got %v on the second request, want the page", err)
+	}
+	if fetches := site.robotsFetches(); fetches != 1 {
+		t.Errorf("robots.txt was fetched %d times, want once", fetches)
+	}
+}
+
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+	return parsed
+}
@@ -0,0 +1,163 @@
+package writers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrAPICredentials wraps errors getting the credentials for a request. Retrying doesn't help with those.
+var ErrAPICredentials = errors.New("API credentials unavailable")
+
+// ErrAPIUnauthorized wraps a 401 or 403 from the API, after a fresh bearer token was tried too
+var ErrAPIUnauthorized = errors.New("API refused the credentials")
+
+// DefaultAPIKeyHeader is the header the API key is sent in unless APIAuth says otherwise
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// APIAuth holds the credentials the writer sends to the API. Any combination can be set, an
+// empty APIAuth sends none.
+type APIAuth struct {
+	APIKey       string      // Static key sent with every request
+	APIKeyHeader string      // Header the key is sent in, DefaultAPIKeyHeader if empty
+	Token        TokenSource // Source of a bearer token for the Authorization header
+
+	// Client certificate for mTLS, both PEM files
+	ClientCertFile string
+	ClientKeyFile  string
+	// CA certificate to verify the API's certificate with, instead of the system roots
+	CACertFile string
+}
+
+// TokenSource supplies bearer tokens
+type TokenSource interface {
+	// Token returns the current token
+	Token(ctx context.Context) (string, error)
+	// Invalidate tells the source the API rejected its token, so the next Token call fetches a new one if it can
+	Invalidate()
+}
+
+// StaticToken is a bearer token that never changes, e.g. one read from the environment
+type StaticToken string
+
+func (t StaticToken) Token(ctx context.Context) (string, error) {
+	return string(t), nil
+}
+
+func (t StaticToken) Invalidate() {}
+
+// FileTokenSource reads the bearer token from a file, so whatever renews the token can rewrite the
+// file while the scraper runs. The file is read again once refresh has passed, and right away
+// after the API rejected the token.
+type FileTokenSource struct {
+	path    string
+	refresh time.Duration
+
+	mu     sync.Mutex
+	token  string
+	readAt time.Time
+}
+
+// NewFileTokenSource creates a token source for the file at path, re-read every refresh
+func NewFileTokenSource(path string, refresh time.Duration) *FileTokenSource {
+	return &FileTokenSource{path: path, refresh: refresh}
+}
+
+func (s *FileTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Since(s.readAt) < s.refresh {
+		return s.token, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", s.path)
+	}
+
+	s.token = token
+	s.readAt = time.Now()
+	return s.token, nil
+}
+
+func (s *FileTokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.token = ""
+}
+
+// enabled reports whether any credentials are set
+func (a APIAuth) enabled() bool {
+	return a.APIKey != "" || a.Token != nil || a.ClientCertFile != ""
+}
+
+// authorize adds the API key and bearer token to req
+func (a APIAuth) authorize(req *http.Request) error {
+	if a.APIKey != "" {
+		header := a.APIKeyHeader
+		if header == "" {
+			header = DefaultAPIKeyHeader
+		}
+		req.Header.Set(header, a.APIKey)
+	}
+
+	if a.Token != nil {
+		token, err := a.Token.Token(req.Context())
+		if err != nil {
+			return fmt.Errorf("%w: failed to get bearer token: %w", ErrAPICredentials, err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return nil
+}
+
+// transport returns the transport for requests to the API, with the client certificate and CA
+// if they are set. Without them it returns nil, so the client uses the default transport.
+func (a APIAuth) transport() (http.RoundTripper, error) {
+	if a.ClientCertFile == "" && a.ClientKeyFile == "" && a.CACertFile == "" {
+		return nil, nil
+	}
+	if (a.ClientCertFile == "") != (a.ClientKeyFile == "") {
+		return nil, errors.New("client certificate and key must be set together")
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if a.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(a.ClientCertFile, a.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if a.CACertFile != "" {
+		pem, err := os.ReadFile(a.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", a.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
@@ -0,0 +1,302 @@
+package writers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// authRequest is what the test API saw of a request
+type authRequest struct {
+	Method        string
+	APIKey        string
+	Authorization string
+}
+
+// authLog records the requests a test API received
+type authLog struct {
+	mu       sync.Mutex
+	requests []authRequest
+}
+
+func (l *authLog) add(r *http.Request, keyHeader string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests = append(l.requests, authRequest{r.Method, r.Header.Get(keyHeader), r.Header.Get("Authorization")})
+}
+
+func (l *authLog) all() []authRequest {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]authRequest(nil), l.requests...)
+}
+
+// createPark answers like an API that doesn't have the park yet and creates it
+func createPark(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// writeOnePark sends a single park through writer and closes it
+func writeOnePark(t *testing.T, writer *APIParkWriter) UpsertStats {
+	t.Helper()
+
+	if err := writer.OnParkScraped(context.Background(), testParkEvent(0)); err != nil {
+		t.Fatalf("OnParkScraped: %v", err)
+	}
+	if err := writer.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return writer.Stats()
+}
+
+func TestAPIKeyIsSentInHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"default header", "", DefaultAPIKeyHeader},
+		{"configured header", "X-Parks-Key", "X-Parks-Key"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var log authLog
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				log.add(r, test.want)
+				createPark(w, r)
+			}))
+			defer server.Close()
+
+			writer := newTestAPIWriter(t, server, APIWriterOptions{Auth: APIAuth{APIKey: "secret-key", APIKeyHeader: test.header}})
+			if stats := writeOnePark(t, writer); stats.Created != 1 {
+				t.Fatalf("got stats %+v, want the park created", stats)
+			}
+
+			want := []authRequest{{"GET", "secret-key", ""}, {"PUT", "secret-key", ""}, {"POST", "secret-key", ""}}
+			if !reflect.DeepEqual(log.all(), want) {
+				t.Errorf("got requests %+v, want %+v", log.all(), want)
+			}
+		})
+	}
+}
+
+func TestBearerTokenIsReReadAfter401(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("expired-token\n"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	// The token was renewed on disk after the writer read it
+	var log authLog
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r, DefaultAPIKeyHeader)
+		if r.Header.Get("Authorization") != "Bearer renewed-token" {
+			if err := os.WriteFile(tokenFile, []byte("renewed-token\n"), 0600); err != nil {
+				t.Errorf("WriteFile: %v", err)
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		createPark(w, r)
+	}))
+	defer server.Close()
+
+	// With an hour between reads, only the 401 makes the source read the file again
+	writer := newTestAPIWriter(t, server, APIWriterOptions{Auth: APIAuth{Token: NewFileTokenSource(tokenFile, time.Hour)}})
+	if stats := writeOnePark(t, writer); stats.Created != 1 {
+		t.Fatalf("got stats %+v, want the park created", stats)
+	}
+
+	want := []authRequest{
+		{"GET", "", "Bearer expired-token"},
+		{"GET", "", "Bearer renewed-token"},
+		{"PUT", "", "Bearer renewed-token"},
+		{"POST", "", "Bearer renewed-token"},
+	}
+	if !reflect.DeepEqual(log.all(), want) {
+		t.Errorf("got requests %+v, want %+v", log.all(), want)
+	}
+}
+
+func TestRejectedStaticTokenIsRetriedOnce(t *testing.T) {
+	var log authLog
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r, DefaultAPIKeyHeader)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	writer := newTestAPIWriter(t, server, APIWriterOptions{Auth: APIAuth{Token: StaticToken("env-token")}})
+	if stats := writeOnePark(t, writer); stats.Failed != 1 {
+		t.Fatalf("got stats %+v, want the park failed", stats)
+	}
+
+	// The lookup is sent once more after the 401, and once that is refused too the park is given up
+	want := []authRequest{
+		{"GET", "", "Bearer env-token"},
+		{"GET", "", "Bearer env-token"},
+	}
+	if !reflect.DeepEqual(log.all(), want) {
+		t.Errorf("got requests %+v, want %+v", log.all(), want)
+	}
+}
+
+func TestMissingTokenFileFailsWithoutRetrying(t *testing.T) {
+	var log authLog
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r, DefaultAPIKeyHeader)
+		createPark(w, r)
+	}))
+	defer server.Close()
+
+	writer := newTestAPIWriter(t, server, APIWriterOptions{Auth: APIAuth{Token: NewFileTokenSource(filepath.Join(t.TempDir(), "missing"), time.Hour)}})
+	if stats := writeOnePark(t, writer); stats.Failed != 1 {
+		t.Fatalf("got stats %+v, want the park failed", stats)
+	}
+	if requests := log.all(); len(requests) != 0 {
+		t.Errorf("sent %+v without a token", requests)
+	}
+}
+
+// writePEM writes a PEM block to a new file in dir and returns its path
+func writePEM(t *testing.T, dir string, name string, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+// newClientCertificate creates a self-signed client certificate for commonName and writes it and
+// its key to dir
+func newClientCertificate(t *testing.T, dir string, commonName string) (*x509.Certificate, string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+
+	return cert, writePEM(t, dir, commonName+".crt", "CERTIFICATE", der), writePEM(t, dir, commonName+".key", "PRIVATE KEY", keyDER)
+}
+
+func TestClientCertificateIsPresented(t *testing.T) {
+	dir := t.TempDir()
+	clientCert, certFile, keyFile := newClientCertificate(t, dir, "park-scraper")
+
+	var mu sync.Mutex
+	var peers []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		peers = append(peers, r.TLS.PeerCertificates[0].Subject.CommonName)
+		mu.Unlock()
+		createPark(w, r)
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	// The API's certificate is only trusted through the CA file
+	caFile := writePEM(t, dir, "api-ca.crt", "CERTIFICATE", server.Certificate().Raw)
+
+	t.Run("with certificate", func(t *testing.T) {
+		auth := APIAuth{ClientCertFile: certFile, ClientKeyFile: keyFile, CACertFile: caFile}
+		writer := newTestAPIWriter(t, server, APIWriterOptions{Auth: auth})
+		if stats := writeOnePark(t, writer); stats.Created != 1 {
+			t.Fatalf("got stats %+v, want the park created", stats)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if want := []string{"park-scraper", "park-scraper", "park-scraper"}; !reflect.DeepEqual(peers, want) {
+			t.Errorf("the API saw client certificates %v, want %v", peers, want)
+		}
+	})
+
+	t.Run("without certificate", func(t *testing.T) {
+		writer := newTestAPIWriter(t, server, APIWriterOptions{Auth: APIAuth{CACertFile: caFile}, MaxRetries: 1})
+		if stats := writeOnePark(t, writer); stats.Failed != 1 {
+			t.Errorf("got stats %+v, want the park refused without a client certificate", stats)
+		}
+	})
+}
+
+func TestBadCertificateFilesFailWriterCreation(t *testing.T) {
+	dir := t.TempDir()
+	_, certFile, keyFile := newClientCertificate(t, dir, "park-scraper")
+	missing := filepath.Join(dir, "missing.pem")
+	notPEM := filepath.Join(dir, "not.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		auth APIAuth
+	}{
+		{"missing certificate", APIAuth{ClientCertFile: missing, ClientKeyFile: keyFile}},
+		{"missing key", APIAuth{ClientCertFile: certFile, ClientKeyFile: missing}},
+		{"unreadable certificate", APIAuth{ClientCertFile: notPEM, ClientKeyFile: keyFile}},
+		{"unreadable key", APIAuth{ClientCertFile: certFile, ClientKeyFile: notPEM}},
+		{"certificate without key", APIAuth{ClientCertFile: certFile}},
+		{"key without certificate", APIAuth{ClientKeyFile: keyFile}},
+		{"missing CA", APIAuth{CACertFile: missing}},
+		{"unreadable CA", APIAuth{CACertFile: notPEM}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			writer, err := NewAPIParkWriter("https://api.example.com", APIWriterOptions{Auth: test.auth})
+			if err == nil {
+				writer.Close(context.Background())
+				t.Fatal("NewAPIParkWriter accepted the bad certificate files")
+			}
+		})
+	}
+}
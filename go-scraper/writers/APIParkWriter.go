@@ -2,46 +2,556 @@ package writers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"scraper/events"
+	"scraper/models"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-type APIParkWriter struct{
+// APIParkWriter upserts scraped parks into the API, keyed by the park code the API derives
+// from name and state, so re-running the scraper updates parks instead of duplicating them.
+//
+// Parks are buffered and sent in batches by a background goroutine, several parks of a batch at
+// a time. As an events.DeferredParkEventSubscriber, a park only counts as handled by the publisher
+// once it was written or reported to OnDeliveryFailed, not when it is buffered. Requests that fail
+// with a connection error, a 5xx or a 429 are retried with exponential backoff or after the
+// API's Retry-After. Parks that still can't be written are reported to the OnDeliveryFailed
+// callback. A circuit breaker stops writing to an API that keeps failing, so a dead API fails
+// parks fast instead of stalling.
+type APIParkWriter struct {
 	baseUrl string
 	client  *http.Client
+	options APIWriterOptions
+	breaker *circuitBreaker
+	onFail  func(event events.ParkScrapedEvent, err error)
+
+	pending   chan pendingPark
+	mu        sync.RWMutex // Keeps OnParkScraped from sending on pending after Close
+	closed    bool
+	ctx       context.Context // Cancelled when Close gives up on the parks still buffered
+	cancel    context.CancelFunc
+	stopped   chan struct{}
+	closeOnce sync.Once
+
+	created   atomic.Int64
+	updated   atomic.Int64
+	unchanged atomic.Int64
+	failed    atomic.Int64
+}
+
+// APIWriterOptions tunes batching, retries and the circuit breaker. Zero fields get the defaults in parentheses.
+type APIWriterOptions struct {
+	BatchSize        int           // Parks sent together (20)
+	Concurrency      int           // Parks of a batch that are sent at the same time (4)
+	FlushInterval    time.Duration // Longest a park waits in the buffer (2s)
+	MaxRetries       int           // Retries of a failed request (4)
+	Backoff          time.Duration // Wait before the first retry, doubled for each further one (500ms)
+	MaxBackoff       time.Duration // Cap on the wait between retries, also on Retry-After (30s)
+	BreakerThreshold int           // Parks failing in a row that open the circuit breaker (5)
+	BreakerCooldown  time.Duration // How long the open breaker refuses writes (30s)
+	Auth             APIAuth       // Credentials sent with every request (none)
+}
+
+// withDefaults fills in the zero fields of o
+func (o APIWriterOptions) withDefaults() APIWriterOptions {
+	if o.BatchSize <= 0 {
+		o.BatchSize = 20
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 4
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = 2 * time.Second
+	}
+	if o.MaxRetries <= 0 {
+		o.MaxRetries = 4
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = 500 * time.Millisecond
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 30 * time.Second
+	}
+	if o.BreakerThreshold <= 0 {
+		o.BreakerThreshold = 5
+	}
+	if o.BreakerCooldown <= 0 {
+		o.BreakerCooldown = 30 * time.Second
+	}
+	return o
 }
 
-func NewAPIParkWriter (url string) *APIParkWriter{
-	return &APIParkWriter{baseUrl: url, client: &http.Client{
-			Timeout: 10 * time.Second,
-		},}
+// ErrAPIUnavailable wraps the error of a request that still failed after every retry
+var ErrAPIUnavailable = errors.New("API unavailable")
+
+// pendingPark is a buffered park with the function that tells the publisher it is done
+type pendingPark struct {
+	event events.ParkScrapedEvent
+	done  func(delivered bool) // May be nil
 }
 
-func (w *APIParkWriter) OnParkScraped(event events.ParkScrapedEvent){
-	// Build the request URL
-	requestURL := fmt.Sprintf("%s/park", w.baseUrl)
+// errWriterClosed is returned for parks handed to the writer after Close
+var errWriterClosed = errors.New("API writer is closed")
+
+// UpsertStats counts what the writer did with the parks it was given
+type UpsertStats struct {
+	Created   int64
+	Updated   int64
+	Unchanged int64
+	Failed    int64
+}
 
+// NewAPIParkWriter creates a writer for the API at url and starts its background sender.
+// Call Close to send what is still buffered.
+func NewAPIParkWriter(url string, options APIWriterOptions) (*APIParkWriter, error) {
+	options = options.withDefaults()
 
-  	jsonData, _ := json.Marshal(event.Park)
-   	bodyReader := bytes.NewReader(jsonData)
-	// Make the HTTP request
-	log.Printf("[APIWriter] Writing park %s to API", event.Park.Name)
-	resp, err := w.client.Post(requestURL, "Application/JSON", bodyReader)
+	transport, err := options.Auth.transport()
 	if err != nil {
-		fmt.Printf("[APIWriter] failed to post park %s \n Error : %w", event.Park.Name, err)
+		return nil, err
+	}
+	if options.Auth.enabled() && !strings.HasPrefix(url, "https://") {
+		log.Printf("[APIWriter] Warning: sending credentials to %s without TLS", url)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &APIParkWriter{
+		baseUrl: url,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+		},
+		options: options,
+		breaker: newCircuitBreaker(options.BreakerThreshold, options.BreakerCooldown),
+		pending: make(chan pendingPark, options.BatchSize),
+		ctx:     ctx,
+		cancel:  cancel,
+		stopped: make(chan struct{}),
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+// OnDeliveryFailed registers a callback for parks that were accepted by OnParkScraped but could
+// not be written to the API. It is called from the writer's background goroutines, possibly
+// for several parks at once.
+func (w *APIParkWriter) OnDeliveryFailed(f func(event events.ParkScrapedEvent, err error)) {
+	w.onFail = f
+}
+
+// Stats returns how many parks were created, updated, left unchanged and failed so far
+func (w *APIParkWriter) Stats() UpsertStats {
+	return UpsertStats{
+		Created:   w.created.Load(),
+		Updated:   w.updated.Load(),
+		Unchanged: w.unchanged.Load(),
+		Failed:    w.failed.Load(),
+	}
+}
+
+// OnParkScraped buffers the park for the next batch. While the circuit breaker is open parks are
+// refused with ErrCircuitOpen, and while the buffer is full it waits for the sender to catch up.
+func (w *APIParkWriter) OnParkScraped(ctx context.Context, event events.ParkScrapedEvent) error {
+	return w.OnParkScrapedDeferred(ctx, event, nil)
+}
+
+// OnParkScrapedDeferred buffers the park like OnParkScraped and calls done with true once the park
+// was written, or with false once it failed and was reported to OnDeliveryFailed
+func (w *APIParkWriter) OnParkScrapedDeferred(ctx context.Context, event events.ParkScrapedEvent, done func(delivered bool)) error {
+	if event.Park == nil {
+		return fmt.Errorf("received nil park in event for %s", event.URL)
+	}
+	if w.breaker.refusing() {
+		return ErrCircuitOpen
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return errWriterClosed
 	}
 
-	// Check response status
-	if resp.StatusCode == 201 || resp.StatusCode == 200 {
-		fmt.Printf("[APIWriter] POST successful. API returned status %d", resp.StatusCode)
-	}else{
-		fmt.Printf("[APIWriter] POST Failed: %d", resp.StatusCode)
+	select {
+	case w.pending <- pendingPark{event: event, done: done}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close sends the parks still buffered and stops the writer. If ctx is done first, the parks
+// still unsent are abandoned and reported as failed. It is safe to call more than once.
+func (w *APIParkWriter) Close(ctx context.Context) error {
+	w.closeOnce.Do(func() {
+		w.mu.Lock()
+		w.closed = true
+		close(w.pending)
+		w.mu.Unlock()
+	})
+
+	select {
+	case <-w.stopped:
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		<-w.stopped
+		return ctx.Err()
+	}
+}
+
+// run collects parks into batches and sends a batch once it is full or the flush interval passes
+func (w *APIParkWriter) run() {
+	defer close(w.stopped)
+	defer w.cancel()
+
+	ticker := time.NewTicker(w.options.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]pendingPark, 0, w.options.BatchSize)
+	for {
+		select {
+		case park, ok := <-w.pending:
+			if !ok {
+				w.sendBatch(batch)
+				return
+			}
+			batch = append(batch, park)
+			if len(batch) < w.options.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		w.sendBatch(batch)
+		batch = batch[:0]
+	}
+}
+
+// sendBatch upserts the parks of batch, several at a time, and returns once all of them are done
+func (w *APIParkWriter) sendBatch(batch []pendingPark) {
+	if len(batch) == 0 {
+		return
+	}
+	workers := min(w.options.Concurrency, len(batch))
+	log.Printf("[APIWriter] Sending batch of %d parks with %d workers", len(batch), workers)
+
+	jobs := make(chan pendingPark)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for park := range jobs {
+				w.sendPark(park)
+			}
+		}()
+	}
+
+	for _, park := range batch {
+		jobs <- park
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// sendPark upserts one park unless the circuit breaker refuses, reporting it if it fails, and
+// then tells the publisher it is done and whether it was written
+func (w *APIParkWriter) sendPark(park pendingPark) {
+	event := park.event
+
+	err := w.breaker.allow()
+	if err == nil {
+		err = w.upsert(w.ctx, event.Park)
+		w.recordOutcome(err)
+	}
+
+	if err != nil {
+		w.failed.Add(1)
+		log.Printf("[APIWriter] Failed to write park %s: %v", event.Park.Name, err)
+		if w.onFail != nil {
+			w.onFail(event, err)
+		}
+	}
+
+	if park.done != nil {
+		park.done(err == nil)
+	}
+}
+
+// recordOutcome tells the circuit breaker what the upsert of a park says about the API. Only a
+// park that was written counts as a success, and an unreachable API or refused credentials count
+// against it. A cancelled write or a park the API rejects says nothing either way.
+func (w *APIParkWriter) recordOutcome(err error) {
+	var changed bool
+	switch {
+	case err == nil:
+		changed = w.breaker.record(true)
+	case errors.Is(err, ErrAPIUnavailable), errors.Is(err, ErrAPICredentials), errors.Is(err, ErrAPIUnauthorized):
+		changed = w.breaker.record(false)
+	default:
+		w.breaker.release()
+	}
+
+	if changed && err != nil {
+		log.Printf("[APIWriter] API failed %d times in a row, pausing writes for %v", w.options.BreakerThreshold, w.options.BreakerCooldown)
+	} else if changed {
+		log.Printf("[APIWriter] API is back, resuming writes")
+	}
+}
+
+// upsert writes one park: parks the API already has with the same data are left alone, others
+// are updated with PUT /park/{parkCode}, and created with POST /park if the API doesn't know them
+func (w *APIParkWriter) upsert(ctx context.Context, park *models.Park) error {
+	parkCode := models.ParkCode(*park)
+
+	// A failed lookup only costs the unchanged check, unless the API is down or won't let us in
+	existing, err := w.getPark(ctx, parkCode)
+	if errors.Is(err, ErrAPIUnavailable) || errors.Is(err, ErrAPICredentials) || errors.Is(err, ErrAPIUnauthorized) || ctx.Err() != nil {
+		return fmt.Errorf("failed to look up park %s: %w", parkCode, err)
+	}
+	if err != nil {
+		log.Printf("[APIWriter] Could not look up park %s: %v", parkCode, err)
+	}
+	if existing != nil && sameParkData(existing, park) {
+		w.unchanged.Add(1)
+		log.Printf("[APIWriter] Park %s is unchanged", parkCode)
+		return nil
+	}
+
+	jsonData, err := json.Marshal(newAPIPark(park))
+	if err != nil {
+		return fmt.Errorf("failed to marshal park %s: %w", park.Name, err)
+	}
+
+	log.Printf("[APIWriter] Writing park %s to API", parkCode)
+	status, _, err := w.do(ctx, http.MethodPut, "/park/"+url.PathEscape(parkCode), jsonData)
+	if err != nil {
+		return fmt.Errorf("failed to update park %s: %w", parkCode, err)
+	}
+	if status != http.StatusNotFound {
+		if !isSuccess(status) {
+			return fmt.Errorf("PUT of park %s failed: API returned status %d", parkCode, status)
+		}
+		w.updated.Add(1)
+		fmt.Printf("[APIWriter] PUT successful. API returned status %d\n", status)
+		return nil
+	}
+
+	// The API doesn't have the park yet
+	status, _, err = w.do(ctx, http.MethodPost, "/park", jsonData)
+	if err != nil {
+		return fmt.Errorf("failed to post park %s: %w", parkCode, err)
+	}
+	if !isSuccess(status) {
+		return fmt.Errorf("POST of park %s failed: API returned status %d", parkCode, status)
+	}
+	w.created.Add(1)
+	fmt.Printf("[APIWriter] POST successful. API returned status %d\n", status)
+	return nil
+}
+
+// do makes a request to the API and returns the response status and body. Connection errors,
+// 5xx and 429 responses are retried with exponential backoff, or after the response's
+// Retry-After if it has one. If they persist the error wraps ErrAPIUnavailable. A 401 or 403 is
+// returned as ErrAPIUnauthorized.
+func (w *APIParkWriter) do(ctx context.Context, method string, path string, body []byte) (int, []byte, error) {
+	backoff := w.options.Backoff
+
+	for attempt := 1; ; attempt++ {
+		status, respBody, retryAfter, err := w.send(ctx, method, path, body)
+		if ctx.Err() != nil {
+			return 0, nil, ctx.Err()
+		}
+		if errors.Is(err, ErrAPICredentials) {
+			return 0, nil, err
+		}
+		if err == nil && (status == http.StatusUnauthorized || status == http.StatusForbidden) {
+			return 0, nil, fmt.Errorf("%w: %s %s returned status %d", ErrAPIUnauthorized, method, path, status)
+		}
+		if err == nil && status < 500 && status != http.StatusTooManyRequests {
+			return status, respBody, nil
+		}
+
+		if err == nil {
+			err = fmt.Errorf("API returned status %d", status)
+		}
+		if attempt > w.options.MaxRetries {
+			return 0, nil, fmt.Errorf("%w: %s %s failed %d times: %w", ErrAPIUnavailable, method, path, attempt, err)
+		}
+
+		wait := backoff
+		if retryAfter > 0 {
+			wait = min(retryAfter, w.options.MaxBackoff)
+		}
+		log.Printf("[APIWriter] %s %s failed (attempt %d/%d), retrying in %v: %v", method, path, attempt, w.options.MaxRetries+1, wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		}
+		backoff = min(backoff*2, w.options.MaxBackoff)
+	}
+}
+
+// send makes a single request and returns the response status, body and Retry-After delay. If
+// the API rejects the bearer token, the request is sent once more with a fresh one.
+func (w *APIParkWriter) send(ctx context.Context, method string, path string, body []byte) (int, []byte, time.Duration, error) {
+	status, respBody, retryAfter, err := w.sendOnce(ctx, method, path, body)
+	if err == nil && status == http.StatusUnauthorized && w.options.Auth.Token != nil {
+		log.Printf("[APIWriter] API rejected the bearer token, refreshing it")
+		w.options.Auth.Token.Invalidate()
+		return w.sendOnce(ctx, method, path, body)
+	}
+	return status, respBody, retryAfter, err
+}
+
+// sendOnce makes a single request with the configured credentials
+func (w *APIParkWriter) sendOnce(ctx context.Context, method string, path string, body []byte) (int, []byte, time.Duration, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, w.baseUrl+path, bodyReader)
+	if err != nil {
+		return 0, nil, 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if err := w.options.Auth.authorize(req); err != nil {
+		return 0, nil, 0, err
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, nil, 0, err
 	}
 	defer resp.Body.Close()
 
-	
-}
\ No newline at end of file
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, nil, 0, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return resp.StatusCode, respBody, parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()), nil
+}
+
+// parseRetryAfter reads a Retry-After header, given either in seconds or as an HTTP date.
+// It returns 0 if the header is missing or invalid.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// apiPark is a park as the API stores it: the body of PUT and POST /park, and what
+// GET /park/{parkCode} returns
+type apiPark struct {
+	Name       string                `json:"name"`
+	StateCode  string                `json:"stateCode"`
+	Latitude   float32               `json:"latitude"`
+	Longitude  float32               `json:"longitude"`
+	Activities []models.ParkActivity `json:"activities"`
+}
+
+// newAPIPark picks what the API keeps of park. The API's Park model has no address, telephone or
+// opening hours, so those only end up in the files FileParkWriter writes, like the field sources.
+func newAPIPark(park *models.Park) apiPark {
+	return apiPark{
+		Name:       park.Name,
+		StateCode:  park.StateCode,
+		Latitude:   park.Latitude,
+		Longitude:  park.Longitude,
+		Activities: park.Activities,
+	}
+}
+
+// getPark fetches the park stored under parkCode, or nil if the API doesn't have it
+func (w *APIParkWriter) getPark(ctx context.Context, parkCode string) (*apiPark, error) {
+	status, body, err := w.do(ctx, http.MethodGet, "/park/"+url.PathEscape(parkCode), nil)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if !isSuccess(status) {
+		return nil, fmt.Errorf("API returned status %d", status)
+	}
+
+	var park apiPark
+	if err := json.Unmarshal(body, &park); err != nil {
+		return nil, fmt.Errorf("failed to decode park: %w", err)
+	}
+	return &park, nil
+}
+
+// sameParkData reports whether the API already stores everything it keeps of park. Coordinates
+// are stored with 6 decimals, and activities come back in no particular order.
+func sameParkData(existing *apiPark, park *models.Park) bool {
+	if existing.Name != park.Name || existing.StateCode != park.StateCode {
+		return false
+	}
+	if !sameCoordinate(existing.Latitude, park.Latitude) || !sameCoordinate(existing.Longitude, park.Longitude) {
+		return false
+	}
+	if len(existing.Activities) != len(park.Activities) {
+		return false
+	}
+
+	stored := sortedActivities(existing.Activities)
+	scraped := sortedActivities(park.Activities)
+	for i := range stored {
+		if stored[i] != scraped[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameCoordinate(a float32, b float32) bool {
+	return math.Abs(float64(a)-float64(b)) < 1e-5
+}
+
+func sortedActivities(activities []models.ParkActivity) []models.ParkActivity {
+	sorted := append([]models.ParkActivity(nil), activities...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+		return sorted[i].Description < sorted[j].Description
+	})
+	return sorted
+}
+
+func isSuccess(status int) bool {
+	return status >= 200 && status < 300
+}
@@ -0,0 +1,438 @@
+package writers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"scraper/events"
+	"scraper/models"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestAPIWriter creates a writer for server that retries quickly
+func newTestAPIWriter(t *testing.T, server *httptest.Server, options APIWriterOptions) *APIParkWriter {
+	t.Helper()
+
+	options.Backoff = time.Millisecond
+	options.MaxBackoff = 10 * time.Millisecond
+	writer, err := NewAPIParkWriter(server.URL, options)
+	if err != nil {
+		t.Fatalf("NewAPIParkWriter: %v", err)
+	}
+	return writer
+}
+
+// testParkEvent is an event for a park with its own park code
+func testParkEvent(i int) events.ParkScrapedEvent {
+	return events.ParkScrapedEvent{
+		Park:      &models.Park{Name: fmt.Sprintf("Test Park %d", i), StateCode: "IL"},
+		StateCode: "IL",
+		URL:       fmt.Sprintf("https://dnr.illinois.gov/parks/park/test-%d", i),
+	}
+}
+
+func TestAPIWriterSendsBatchConcurrently(t *testing.T) {
+	const parks = 4
+
+	// Every lookup waits until all parks of the batch are being looked up at once
+	var mu sync.Mutex
+	lookups := 0
+	allLooking := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			mu.Lock()
+			lookups++
+			if lookups == parks {
+				close(allLooking)
+			}
+			mu.Unlock()
+
+			select {
+			case <-allLooking:
+				w.WriteHeader(http.StatusNotFound)
+			case <-time.After(5 * time.Second):
+				w.WriteHeader(http.StatusGatewayTimeout)
+			}
+		case http.MethodPut:
+			w.WriteHeader(http.StatusNotFound)
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	writer := newTestAPIWriter(t, server, APIWriterOptions{BatchSize: parks, Concurrency: parks, MaxRetries: 1})
+	for i := range parks {
+		if err := writer.OnParkScraped(context.Background(), testParkEvent(i)); err != nil {
+			t.Fatalf("OnParkScraped: %v", err)
+		}
+	}
+	if err := writer.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if stats := writer.Stats(); stats.Created != parks || stats.Failed != 0 {
+		t.Errorf("got stats %+v, want %d parks created", stats, parks)
+	}
+}
+
+func TestAPIWriterIsDoneWithParkOnlyOnceWritten(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/test-park-0-il"):
+			<-release
+			w.WriteHeader(http.StatusOK)
+		default:
+			// The API rejects the second park for good
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+	}))
+	defer server.Close()
+
+	writer := newTestAPIWriter(t, server, APIWriterOptions{BatchSize: 1})
+	var mu sync.Mutex
+	var reported []string
+	writer.OnDeliveryFailed(func(event events.ParkScrapedEvent, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, event.URL)
+	})
+
+	written := make(chan bool, 1)
+	if err := writer.OnParkScrapedDeferred(context.Background(), testParkEvent(0), func(delivered bool) { written <- delivered }); err != nil {
+		t.Fatalf("OnParkScrapedDeferred: %v", err)
+	}
+	select {
+	case <-written:
+		t.Fatal("the park was done while its upsert was still in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case delivered := <-written:
+		if !delivered {
+			t.Error("the written park was done as not delivered")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the park was never done after it was written")
+	}
+	if stats := writer.Stats(); stats.Updated != 1 {
+		t.Errorf("got stats %+v, want the park updated", stats)
+	}
+
+	// A park that fails is reported before it is done, as not delivered
+	failed := make(chan []string, 1)
+	err := writer.OnParkScrapedDeferred(context.Background(), testParkEvent(1), func(delivered bool) {
+		if delivered {
+			t.Error("the failed park was done as delivered")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		failed <- append([]string(nil), reported...)
+	})
+	if err != nil {
+		t.Fatalf("OnParkScrapedDeferred: %v", err)
+	}
+	select {
+	case got := <-failed:
+		if want := []string{testParkEvent(1).URL}; !reflect.DeepEqual(got, want) {
+			t.Errorf("reported %v by the time the park was done, want %v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the failed park was never done")
+	}
+
+	if err := writer.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestAPIWriterSendsOnlyWhatTheAPIStores(t *testing.T) {
+	bodies := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode body: %v", err)
+		}
+		bodies <- body
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	event := testParkEvent(0)
+	event.Park.Address = "2678 E 875th Rd, Oglesby, IL 61348"
+	event.Park.Telephone = "815-667-4726"
+	event.Park.OpeningHours = []string{"Mo-Su 07:00-22:00"}
+	event.Park.FieldSources = map[string]string{"name": "css"}
+	event.Park.Activities = []models.ParkActivity{{Name: "Hiking"}}
+
+	writer := newTestAPIWriter(t, server, APIWriterOptions{})
+	if err := writer.OnParkScraped(context.Background(), event); err != nil {
+		t.Fatalf("OnParkScraped: %v", err)
+	}
+	if err := writer.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	body := <-bodies
+	keys := make([]string, 0, len(body))
+	for key := range body {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if want := []string{"activities", "latitude", "longitude", "name", "stateCode"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("sent fields %v, want only %v", keys, want)
+	}
+}
+
+func TestAPIWriterWaitsForRetryAfter(t *testing.T) {
+	var mu sync.Mutex
+	var received []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, time.Now())
+		attempt := len(received)
+		mu.Unlock()
+
+		switch attempt {
+		case 1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	// MaxBackoff caps Retry-After, which keeps the test short but far above Backoff
+	const maxBackoff = 200 * time.Millisecond
+	writer, err := NewAPIParkWriter(server.URL, APIWriterOptions{Backoff: time.Millisecond, MaxBackoff: maxBackoff, MaxRetries: 2})
+	if err != nil {
+		t.Fatalf("NewAPIParkWriter: %v", err)
+	}
+	defer writer.Close(context.Background())
+
+	status, _, err := writer.do(context.Background(), http.MethodGet, "/park/test-park-0-il", nil)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("do() = %d, %v, want 200 after the retries", status, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 3 {
+		t.Fatalf("the API got %d requests, want 3", len(received))
+	}
+	for i := 1; i < len(received); i++ {
+		if gap := received[i].Sub(received[i-1]); gap < maxBackoff {
+			t.Errorf("retry %d came %v after a Retry-After, want at least %v", i, gap, maxBackoff)
+		}
+	}
+}
+
+func TestAPIWriterRetriesOnlyWhatMayRecover(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		requests int
+		wantErr  error
+	}{
+		{"unavailable", http.StatusServiceUnavailable, 3, ErrAPIUnavailable},
+		{"too many requests", http.StatusTooManyRequests, 3, ErrAPIUnavailable},
+		{"unauthorized", http.StatusUnauthorized, 1, ErrAPIUnauthorized},
+		{"forbidden", http.StatusForbidden, 1, ErrAPIUnauthorized},
+		{"rejected", http.StatusUnprocessableEntity, 1, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var log authLog
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				log.add(r, DefaultAPIKeyHeader)
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+
+			writer := newTestAPIWriter(t, server, APIWriterOptions{MaxRetries: 2})
+			defer writer.Close(context.Background())
+
+			status, _, err := writer.do(context.Background(), http.MethodPut, "/park/test-park-0-il", []byte("{}"))
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("do() = %d, %v, want %v", status, err, test.wantErr)
+			}
+			if test.wantErr == nil && status != test.status {
+				t.Errorf("got status %d, want %d", status, test.status)
+			}
+			if requests := len(log.all()); requests != test.requests {
+				t.Errorf("the API got %d requests, want %d", requests, test.requests)
+			}
+		})
+	}
+}
+
+// writeParkAndWait hands park i to writer and waits until the writer is done with it
+func writeParkAndWait(t *testing.T, writer *APIParkWriter, i int) error {
+	t.Helper()
+
+	done := make(chan struct{})
+	if err := writer.OnParkScrapedDeferred(context.Background(), testParkEvent(i), func(bool) { close(done) }); err != nil {
+		return err
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("park %d was never done", i)
+	}
+	return nil
+}
+
+func TestAPIWriterCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		wantOpen   bool
+		wantFailed int64
+	}{
+		{"opens when the API is down", http.StatusServiceUnavailable, true, 2},
+		{"opens when the credentials are refused", http.StatusUnauthorized, true, 2},
+		{"stays closed when parks are rejected", http.StatusUnprocessableEntity, false, 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var log authLog
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				log.add(r, DefaultAPIKeyHeader)
+				if r.Method == http.MethodGet {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+
+			writer := newTestAPIWriter(t, server, APIWriterOptions{
+				BatchSize: 1, MaxRetries: 1, BreakerThreshold: 2, BreakerCooldown: time.Hour,
+			})
+			defer writer.Close(context.Background())
+
+			for i := range 2 {
+				if err := writeParkAndWait(t, writer, i); err != nil {
+					t.Fatalf("park %d was refused: %v", i, err)
+				}
+			}
+			sent := len(log.all())
+
+			err := writeParkAndWait(t, writer, 2)
+			if test.wantOpen && !errors.Is(err, ErrCircuitOpen) {
+				t.Errorf("got %v after two failed parks, want %v", err, ErrCircuitOpen)
+			}
+			if !test.wantOpen && err != nil {
+				t.Errorf("got %v after two rejected parks, want the park accepted", err)
+			}
+			if test.wantOpen && len(log.all()) != sent {
+				t.Errorf("the API got %d requests while the breaker was open", len(log.all())-sent)
+			}
+			if stats := writer.Stats(); stats.Failed != test.wantFailed {
+				t.Errorf("got stats %+v, want %d failed", stats, test.wantFailed)
+			}
+		})
+	}
+}
+
+func TestAPIWriterUpsert(t *testing.T) {
+	park := &models.Park{
+		Name:       "Starved Rock State Park",
+		StateCode:  "IL",
+		Latitude:   41.3197,
+		Longitude:  -88.9948,
+		Activities: []models.ParkActivity{{Name: "Hiking"}, {Name: "Fishing"}},
+	}
+	stored, err := json.Marshal(newAPIPark(park))
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		responses map[string]int // "METHOD path" -> status, 404 for anything else
+		want      []string
+		wantStats UpsertStats
+	}{
+		{
+			name:      "unchanged",
+			responses: map[string]int{"GET /park/starved-rock-state-park-il": http.StatusOK},
+			want:      []string{"GET /park/starved-rock-state-park-il"},
+			wantStats: UpsertStats{Unchanged: 1},
+		},
+		{
+			name:      "updated",
+			responses: map[string]int{"PUT /park/starved-rock-state-park-il": http.StatusNoContent},
+			want:      []string{"GET /park/starved-rock-state-park-il", "PUT /park/starved-rock-state-park-il"},
+			wantStats: UpsertStats{Updated: 1},
+		},
+		{
+			name:      "created after the PUT finds nothing",
+			responses: map[string]int{"POST /park": http.StatusCreated},
+			want:      []string{"GET /park/starved-rock-state-park-il", "PUT /park/starved-rock-state-park-il", "POST /park"},
+			wantStats: UpsertStats{Created: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var requests []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				request := r.Method + " " + r.URL.Path
+				mu.Lock()
+				requests = append(requests, request)
+				mu.Unlock()
+
+				status, ok := test.responses[request]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(status)
+				if r.Method == http.MethodGet {
+					w.Write(stored)
+				}
+			}))
+			defer server.Close()
+
+			writer := newTestAPIWriter(t, server, APIWriterOptions{})
+			defer writer.Close(context.Background())
+
+			if err := writer.upsert(context.Background(), park); err != nil {
+				t.Fatalf("upsert: %v", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(requests, test.want) {
+				t.Errorf("sent %v, want %v", requests, test.want)
+			}
+			if stats := writer.Stats(); stats != test.wantStats {
+				t.Errorf("got stats %+v, want %+v", stats, test.wantStats)
+			}
+		})
+	}
+}
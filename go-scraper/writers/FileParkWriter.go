@@ -1,7 +1,9 @@
 package writers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -28,17 +30,15 @@ func NewParkJSONWriter(outputDir string) *FileParkWriter {
 }
 
 // OnParkScraped is called when a park is scraped - writes it to a JSON file
-func (w *FileParkWriter) OnParkScraped(event events.ParkScrapedEvent) {
+func (w *FileParkWriter) OnParkScraped(ctx context.Context, event events.ParkScrapedEvent) error {
 	if event.Park == nil {
-		log.Printf("[JSONWriter] Received nil park in event")
-		return
+		return fmt.Errorf("received nil park in event for %s", event.URL)
 	}
 
 	// Create directory structure: output/{StateCode}/
 	stateDir := filepath.Join(w.outputDir, event.StateCode)
 	if err := os.MkdirAll(stateDir, 0755); err != nil {
-		log.Printf("[JSONWriter] Failed to create directory %s: %v", stateDir, err)
-		return
+		return fmt.Errorf("failed to create directory %s: %w", stateDir, err)
 	}
 
 	// Generate filename from park name: "Starved Rock State Park" -> "starved-rock-state-park.json"
@@ -48,17 +48,16 @@ func (w *FileParkWriter) OnParkScraped(event events.ParkScrapedEvent) {
 	// Marshal park to JSON with indentation
 	jsonData, err := json.MarshalIndent(event.Park, "", "  ")
 	if err != nil {
-		log.Printf("[JSONWriter] Failed to marshal park %s: %v", event.Park.Name, err)
-		return
+		return fmt.Errorf("failed to marshal park %s: %w", event.Park.Name, err)
 	}
 
 	// Write to file
 	if err := os.WriteFile(filepath, jsonData, 0644); err != nil {
-		log.Printf("[JSONWriter] Failed to write file %s: %v", filepath, err)
-		return
+		return fmt.Errorf("failed to write file %s: %w", filepath, err)
 	}
 
 	log.Printf("[JSONWriter] ✓ Wrote %s to %s (%d bytes)", event.Park.Name, filepath, len(jsonData))
+	return nil
 }
 
 // generateFilename creates a kebab-case filename from park name
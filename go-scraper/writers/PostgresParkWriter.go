@@ -0,0 +1,123 @@
+package writers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"scraper/events"
+	"scraper/models"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresParkWriter writes scraped parks straight into the parks and activities tables of
+// database/init/01-init-schema.sql, so the database can be loaded without the API running.
+// Parks are upserted on the same park_code the API uses, and their activities are replaced,
+// all in one transaction per park.
+type PostgresParkWriter struct {
+	pool *pgxpool.Pool
+
+	created atomic.Int64
+	updated atomic.Int64
+}
+
+// upsertParkSQL inserts a park or updates the one with the same park_code. The location column
+// is filled by the table's trigger. xmax is 0 only for a row this statement inserted.
+const upsertParkSQL = `
+INSERT INTO parks (name, park_code, park_url, state_code, latitude, longitude)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (park_code) DO UPDATE SET
+    name = EXCLUDED.name,
+    park_url = EXCLUDED.park_url,
+    state_code = EXCLUDED.state_code,
+    latitude = EXCLUDED.latitude,
+    longitude = EXCLUDED.longitude
+RETURNING id, (xmax = 0) AS inserted`
+
+// NewPostgresParkWriter connects to the database at databaseURL, a postgres:// URL or key=value
+// connection string, and checks that it is reachable. Call Close when done.
+func NewPostgresParkWriter(ctx context.Context, databaseURL string) (*PostgresParkWriter, error) {
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database URL: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	return &PostgresParkWriter{pool: pool}, nil
+}
+
+// Stats returns how many parks were created and updated so far
+func (w *PostgresParkWriter) Stats() UpsertStats {
+	return UpsertStats{
+		Created: w.created.Load(),
+		Updated: w.updated.Load(),
+	}
+}
+
+// OnParkScraped upserts the park and replaces its activities. Nothing is written if any of it fails.
+func (w *PostgresParkWriter) OnParkScraped(ctx context.Context, event events.ParkScrapedEvent) error {
+	park := event.Park
+	if park == nil {
+		return fmt.Errorf("received nil park in event for %s", event.URL)
+	}
+	parkCode := models.ParkCode(*park)
+
+	var inserted bool
+	err := pgx.BeginFunc(ctx, w.pool, func(tx pgx.Tx) error {
+		var parkID int
+		err := tx.QueryRow(ctx, upsertParkSQL,
+			park.Name, parkCode, nullIfEmpty(event.URL), park.StateCode, float64(park.Latitude), float64(park.Longitude),
+		).Scan(&parkID, &inserted)
+		if err != nil {
+			return fmt.Errorf("failed to upsert park: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, "DELETE FROM activities WHERE park_id = $1", parkID); err != nil {
+			return fmt.Errorf("failed to delete old activities: %w", err)
+		}
+
+		rows := make([][]any, 0, len(park.Activities))
+		for _, activity := range park.Activities {
+			rows = append(rows, []any{parkID, activity.Name, activity.Description})
+		}
+		_, err = tx.CopyFrom(ctx,
+			pgx.Identifier{"activities"},
+			[]string{"park_id", "name", "description"},
+			pgx.CopyFromRows(rows),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert activities: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write park %s to database: %w", parkCode, err)
+	}
+
+	if inserted {
+		w.created.Add(1)
+		log.Printf("[PostgresWriter] Created park %s with %d activities", parkCode, len(park.Activities))
+	} else {
+		w.updated.Add(1)
+		log.Printf("[PostgresWriter] Updated park %s with %d activities", parkCode, len(park.Activities))
+	}
+	return nil
+}
+
+// Close closes the connections to the database
+func (w *PostgresParkWriter) Close() {
+	w.pool.Close()
+}
+
+// nullIfEmpty stores an empty string as NULL
+func nullIfEmpty(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,258 @@
+package writers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"scraper/events"
+	"scraper/models"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SeedSQLWriter collects scraped parks and writes them as seed SQL for the parks and activities
+// tables, like database/init/02-seed-data.sql. The output only depends on the parks collected:
+// parks are ordered by park code, their ids are derived from the park code, and activities are
+// sorted, so the same parks always give the same file and a park keeps its id when others are
+// added or removed.
+type SeedSQLWriter struct {
+	mu    sync.Mutex
+	parks map[string]seedPark // By park code, a park scraped again replaces the earlier one
+}
+
+type seedPark struct {
+	park models.Park
+	url  string
+}
+
+// NewSeedSQLWriter creates an empty seed SQL writer
+func NewSeedSQLWriter() *SeedSQLWriter {
+	return &SeedSQLWriter{parks: make(map[string]seedPark)}
+}
+
+// OnParkScraped adds the park to the seed, with the page it was scraped from as its park_url
+func (w *SeedSQLWriter) OnParkScraped(ctx context.Context, event events.ParkScrapedEvent) error {
+	if event.Park == nil {
+		return fmt.Errorf("received nil park in event for %s", event.URL)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.parks[models.ParkCode(*event.Park)] = seedPark{park: *event.Park, url: event.URL}
+	return nil
+}
+
+// Len returns how many parks the seed holds
+func (w *SeedSQLWriter) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return len(w.parks)
+}
+
+// WriteSQL writes the seed SQL for the parks collected so far to out
+func (w *SeedSQLWriter) WriteSQL(out io.Writer) error {
+	w.mu.Lock()
+	codes := make([]string, 0, len(w.parks))
+	for code := range w.parks {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	parks := make([]seedPark, len(codes))
+	for i, code := range codes {
+		parks[i] = w.parks[code]
+	}
+	w.mu.Unlock()
+
+	var sql strings.Builder
+	sql.WriteString("-- Seed data for the parks and activities tables\n")
+	sql.WriteString("-- Auto-generated by the scraper's seed-sql command\n")
+	sql.WriteString("-- DO NOT EDIT THIS FILE MANUALLY - regenerate using: cd go-scraper && go run . seed-sql\n\n")
+	sql.WriteString("BEGIN;\n\n")
+
+	ids := seedParkIDs(codes)
+	for i, seed := range parks {
+		id := ids[i]
+		park := seed.park
+		fmt.Fprintf(&sql, "INSERT INTO parks (id, name, park_code, park_url, state_code, latitude, longitude) VALUES (%d, %s, %s, %s, %s, %s, %s);\n",
+			id, sqlString(park.Name), sqlString(codes[i]), sqlNullableString(seed.url), sqlString(park.StateCode),
+			sqlCoordinate(park.Latitude), sqlCoordinate(park.Longitude))
+
+		for _, activity := range uniqueActivities(park.Activities) {
+			fmt.Fprintf(&sql, "INSERT INTO activities (park_id, name, description) VALUES (%d, %s, %s);\n",
+				id, sqlString(activity.Name), sqlString(activity.Description))
+		}
+		sql.WriteString("\n")
+	}
+
+	sql.WriteString("-- Reset sequences to continue after the seeded rows\n")
+	fmt.Fprintf(&sql, "SELECT setval('parks_id_seq', %d, false);\n", slices.Max(append(ids, 0))+1)
+	sql.WriteString("SELECT setval('activities_id_seq', COALESCE((SELECT MAX(id) FROM activities), 0) + 1, false);\n\n")
+	sql.WriteString("COMMIT;\n\n")
+	fmt.Fprintf(&sql, "-- Inserted %d parks\n", len(parks))
+
+	_, err := io.WriteString(out, sql.String())
+	return err
+}
+
+// maxSeedParkID bounds the ids of seeded parks. The sequence is reset past the largest seeded id,
+// so parks the API adds later get ids just above this rather than near the top of SERIAL's range.
+// Dense ids in park-code order would keep the sequence lower still, but adding a park would then
+// shift the id of every park after it. With about 6,600 state parks in the US, a million ids keep
+// hash collisions, and with them ids that can move, down to a few dozen.
+const maxSeedParkID = 1_000_000
+
+// seedParkIDs returns the id of each of the sorted park codes, a hash of the code between 1 and
+// maxSeedParkID. Of two codes with the same hash, the later one takes the next free id, which is
+// the only way adding a park can move another one.
+func seedParkIDs(codes []string) []int {
+	ids := make([]int, len(codes))
+	taken := make(map[int]bool, len(codes))
+	for i, code := range codes {
+		hash := fnv.New32a()
+		hash.Write([]byte(code))
+		id := int(hash.Sum32()%maxSeedParkID) + 1
+		for taken[id] {
+			id = id%maxSeedParkID + 1
+		}
+		taken[id] = true
+		ids[i] = id
+	}
+	return ids
+}
+
+// WriteFile writes the seed SQL to path, replacing the file only once all of it is written
+func (w *SeedSQLWriter) WriteFile(path string) error {
+	var sql bytes.Buffer
+	if err := w.WriteSQL(&sql); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write seed SQL: %w", err)
+	}
+	if _, err := tmp.Write(sql.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write seed SQL: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write seed SQL: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write seed SQL: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadDir adds the parks LoadParkJSONFiles finds in dataDir to w. A missing dataDir adds nothing.
+func (w *SeedSQLWriter) LoadDir(ctx context.Context, dataDir string) error {
+	loaded, err := LoadParkJSONFiles(dataDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, event := range loaded {
+		if err := w.OnParkScraped(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stateDirName matches the state directories FileParkWriter writes parks to
+var stateDirName = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// LoadParkJSONFiles reads back the parks FileParkWriter wrote to dataDir, one directory per
+// state, as events in file name order. Other directories under dataDir are ignored. The files
+// don't record the page a park came from, so the events have no URL.
+func LoadParkJSONFiles(dataDir string) ([]events.ParkScrapedEvent, error) {
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	loaded := make([]events.ParkScrapedEvent, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() || !stateDirName.MatchString(entry.Name()) {
+			continue
+		}
+
+		files, err := filepath.Glob(filepath.Join(dataDir, entry.Name(), "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read park file: %w", err)
+			}
+
+			var park models.Park
+			if err := json.Unmarshal(data, &park); err != nil {
+				return nil, fmt.Errorf("failed to decode %s: %w", file, err)
+			}
+			if park.Name == "" {
+				return nil, fmt.Errorf("%s has no park name", file)
+			}
+			if park.StateCode == "" {
+				park.StateCode = entry.Name()
+			}
+
+			loaded = append(loaded, events.ParkScrapedEvent{Park: &park, StateCode: entry.Name()})
+		}
+	}
+
+	return loaded, nil
+}
+
+// uniqueActivities returns activities sorted by name and description, without exact duplicates
+func uniqueActivities(activities []models.ParkActivity) []models.ParkActivity {
+	sorted := sortedActivities(activities)
+	unique := make([]models.ParkActivity, 0, len(sorted))
+	for i, activity := range sorted {
+		if i > 0 && activity == sorted[i-1] {
+			continue
+		}
+		unique = append(unique, activity)
+	}
+	return unique
+}
+
+// sqlString quotes value as an SQL string literal. NUL bytes can't be stored in text columns and are dropped.
+func sqlString(value string) string {
+	value = strings.ReplaceAll(value, "\x00", "")
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func sqlNullableString(value string) string {
+	if value == "" {
+		return "NULL"
+	}
+	return sqlString(value)
+}
+
+// sqlCoordinate formats a coordinate with the fewest digits that read back as the same float32
+func sqlCoordinate(value float32) string {
+	return strconv.FormatFloat(float64(value), 'f', -1, 32)
+}
@@ -0,0 +1,133 @@
+package writers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"scraper/models"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// seedSQL collects the events of parks in order and returns the seed SQL for them
+func seedSQL(t *testing.T, parks ...int) string {
+	t.Helper()
+
+	writer := NewSeedSQLWriter()
+	for _, i := range parks {
+		event := testParkEvent(i)
+		event.Park.Activities = []models.ParkActivity{{Name: "Hiking"}, {Name: "Camping"}}
+		if err := writer.OnParkScraped(context.Background(), event); err != nil {
+			t.Fatalf("OnParkScraped: %v", err)
+		}
+	}
+
+	var sql strings.Builder
+	if err := writer.WriteSQL(&sql); err != nil {
+		t.Fatalf("WriteSQL: %v", err)
+	}
+	return sql.String()
+}
+
+var seedParkInsert = regexp.MustCompile(`(?m)^INSERT INTO parks \(.*\) VALUES \((\d+), '[^']*', '([^']*)'`)
+var seedActivityInsert = regexp.MustCompile(`(?m)^INSERT INTO activities \(.*\) VALUES \((\d+),`)
+
+// seededIDs returns the id each park of the seed SQL is inserted with, by park code
+func seededIDs(t *testing.T, sql string) map[string]int {
+	t.Helper()
+
+	ids := make(map[string]int)
+	for _, match := range seedParkInsert.FindAllStringSubmatch(sql, -1) {
+		id, err := strconv.Atoi(match[1])
+		if err != nil {
+			t.Fatalf("bad park id in %q: %v", match[0], err)
+		}
+		ids[match[2]] = id
+	}
+	return ids
+}
+
+func TestSeedSQLIsTheSameForTheSameParks(t *testing.T) {
+	first := seedSQL(t, 0, 1, 2, 3)
+	if again := seedSQL(t, 0, 1, 2, 3); again != first {
+		t.Errorf("the same parks gave different SQL:\n%s\nand\n%s", first, again)
+	}
+	// Neither the order parks are scraped in nor scraping one again changes the file
+	if reordered := seedSQL(t, 3, 1, 0, 2, 1); reordered != first {
+		t.Errorf("the same parks in another order gave different SQL:\n%s\nand\n%s", first, reordered)
+	}
+}
+
+func TestSeedSQLKeepsIDsWhenParksAreAdded(t *testing.T) {
+	before := seededIDs(t, seedSQL(t, 1, 3))
+	after := seededIDs(t, seedSQL(t, 0, 1, 2, 3))
+
+	if len(before) != 2 || len(after) != 4 {
+		t.Fatalf("got ids %v and %v, want 2 and 4 parks", before, after)
+	}
+	for code, id := range before {
+		if after[code] != id {
+			t.Errorf("adding parks moved %s from id %d to %d", code, id, after[code])
+		}
+	}
+}
+
+func TestSeedSQLActivitiesUseTheirParksID(t *testing.T) {
+	sql := seedSQL(t, 0)
+	ids := seededIDs(t, sql)
+	parkID := ids[models.ParkCode(*testParkEvent(0).Park)]
+
+	activities := seedActivityInsert.FindAllStringSubmatch(sql, -1)
+	if len(activities) != 2 {
+		t.Fatalf("got %d activities, want 2:\n%s", len(activities), sql)
+	}
+	for _, activity := range activities {
+		if activity[1] != strconv.Itoa(parkID) {
+			t.Errorf("got activity %q, want it for park id %d", activity[0], parkID)
+		}
+	}
+	if want := "SELECT setval('parks_id_seq', " + strconv.Itoa(parkID+1) + ", false);"; !strings.Contains(sql, want) {
+		t.Errorf("the parks sequence isn't reset past the seeded id, want %q in:\n%s", want, sql)
+	}
+}
+
+func TestSeedParkIDs(t *testing.T) {
+	tests := []struct {
+		name  string
+		codes []string
+		want  []int
+	}{
+		{"none", nil, []int{}},
+		{"derived from the code", []string{"park-76751-il"}, []int{639515}},
+		// The two codes have the same hash, so the later one takes the next id
+		{"same hash", []string{"park-2562-il", "park-6588-il"}, []int{515850, 515851}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := seedParkIDs(test.codes); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("seedParkIDs(%v) = %v, want %v", test.codes, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSeedParkIDsStayBounded(t *testing.T) {
+	codes := make([]string, 10000)
+	for i := range codes {
+		codes[i] = fmt.Sprintf("park-%05d-il", i)
+	}
+
+	seen := make(map[int]bool, len(codes))
+	for i, id := range seedParkIDs(codes) {
+		if id < 1 || id > maxSeedParkID {
+			t.Fatalf("%s got id %d, want it between 1 and %d", codes[i], id, maxSeedParkID)
+		}
+		if seen[id] {
+			t.Fatalf("%s got id %d, which another park already has", codes[i], id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,83 @@
+package writers
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned for writes that are refused because the API failed too often in a row
+var ErrCircuitOpen = errors.New("circuit breaker is open, the API is considered down")
+
+// circuitBreaker stops writes to an API that keeps failing. After threshold failures in a row it
+// opens and refuses writes for cooldown, then lets a single trial write through: if that works
+// it closes again, otherwise it stays open for another cooldown.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	open      bool
+	openedAt  time.Time
+	trial     bool             // A trial write is in progress
+	now       func() time.Time // The clock, time.Now outside of tests
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow returns ErrCircuitOpen if a write may not go ahead now. Every write it allows must be
+// followed by a call to record or release.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	if b.trial || b.now().Sub(b.openedAt) < b.cooldown {
+		return ErrCircuitOpen
+	}
+	b.trial = true
+	return nil
+}
+
+// refusing reports whether allow would refuse a write right now, without starting a trial
+func (b *circuitBreaker) refusing() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.open && (b.trial || b.now().Sub(b.openedAt) < b.cooldown)
+}
+
+// release ends an allowed write whose outcome says nothing about the API, like a cancelled one,
+// without counting it either way. A trial it ends lets the next write through as a new trial.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+// record reports the outcome of an allowed write. It returns whether the breaker changed
+// between open and closed, so callers can log it.
+func (b *circuitBreaker) record(success bool) (changed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasOpen := b.open
+	b.trial = false
+	if success {
+		b.failures = 0
+		b.open = false
+		return wasOpen
+	}
+
+	b.failures++
+	if wasOpen || b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = b.now()
+	}
+	return !wasOpen && b.open
+}
@@ -0,0 +1,123 @@
+package writers
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// breakerStep is one thing that happens to a circuit breaker in a test
+type breakerStep struct {
+	op   string // "allow", "succeed", "fail", "release" or "wait"
+	wait time.Duration
+	want bool // allow: whether the write may go ahead; succeed and fail: whether the breaker changed
+}
+
+func allowed() breakerStep                       { return breakerStep{op: "allow", want: true} }
+func refused() breakerStep                       { return breakerStep{op: "allow", want: false} }
+func succeed(changed bool) breakerStep           { return breakerStep{op: "succeed", want: changed} }
+func fail(changed bool) breakerStep              { return breakerStep{op: "fail", want: changed} }
+func release() breakerStep                       { return breakerStep{op: "release"} }
+func waitFor(duration time.Duration) breakerStep { return breakerStep{op: "wait", wait: duration} }
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	const cooldown = 30 * time.Second
+
+	tests := []struct {
+		name  string
+		steps []breakerStep
+	}{
+		{"stays closed below the threshold", []breakerStep{
+			allowed(), fail(false), allowed(), fail(false), allowed(),
+		}},
+		{"a success resets the count", []breakerStep{
+			allowed(), fail(false), allowed(), fail(false), allowed(), succeed(false),
+			allowed(), fail(false), allowed(), fail(false), allowed(),
+		}},
+		{"opens at the threshold", []breakerStep{
+			allowed(), fail(false), allowed(), fail(false), allowed(), fail(true),
+			refused(), waitFor(cooldown - time.Second), refused(),
+		}},
+		{"half-open lets one trial through", []breakerStep{
+			allowed(), fail(false), allowed(), fail(false), allowed(), fail(true),
+			waitFor(cooldown), allowed(), refused(),
+		}},
+		{"a successful trial closes", []breakerStep{
+			allowed(), fail(false), allowed(), fail(false), allowed(), fail(true),
+			waitFor(cooldown), allowed(), succeed(true), allowed(), fail(false), allowed(),
+		}},
+		{"a failed trial reopens for another cooldown", []breakerStep{
+			allowed(), fail(false), allowed(), fail(false), allowed(), fail(true),
+			waitFor(cooldown), allowed(), fail(false),
+			refused(), waitFor(cooldown - time.Second), refused(), waitFor(time.Second), allowed(),
+		}},
+		{"a released write doesn't count", []breakerStep{
+			allowed(), fail(false), allowed(), fail(false), allowed(), release(), allowed(), succeed(false),
+			allowed(), fail(false), allowed(), fail(false), allowed(), release(), allowed(), fail(true),
+		}},
+		{"a released trial lets the next write try", []breakerStep{
+			allowed(), fail(false), allowed(), fail(false), allowed(), fail(true),
+			waitFor(cooldown), allowed(), release(), allowed(), succeed(true),
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+			breaker := newCircuitBreaker(3, cooldown)
+			breaker.now = func() time.Time { return now }
+
+			for i, step := range test.steps {
+				switch step.op {
+				case "allow":
+					err := breaker.allow()
+					if step.want && err != nil {
+						t.Fatalf("step %d: allow() = %v, want the write to go ahead", i, err)
+					}
+					if !step.want && !errors.Is(err, ErrCircuitOpen) {
+						t.Fatalf("step %d: allow() = %v, want %v", i, err, ErrCircuitOpen)
+					}
+					// Only a write let through without starting a trial leaves the breaker accepting writes
+					if wantRefusing := err != nil || breaker.trial; breaker.refusing() != wantRefusing {
+						t.Fatalf("step %d: refusing() = %v after allow() = %v, want %v", i, breaker.refusing(), err, wantRefusing)
+					}
+				case "succeed", "fail":
+					if changed := breaker.record(step.op == "succeed"); changed != step.want {
+						t.Fatalf("step %d: record(%v) = %v, want %v", i, step.op == "succeed", changed, step.want)
+					}
+				case "release":
+					breaker.release()
+				case "wait":
+					now = now.Add(step.wait)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+	}{
+		{"missing", "", 0},
+		{"seconds", "120", 2 * time.Minute},
+		{"zero seconds", "0", 0},
+		{"negative seconds", "-5", 0},
+		{"HTTP date", "Sun, 01 Jun 2025 12:00:45 GMT", 45 * time.Second},
+		{"RFC 850 date", "Sunday, 01-Jun-25 12:01:00 GMT", time.Minute},
+		{"date in the past", "Sun, 01 Jun 2025 11:59:00 GMT", 0},
+		{"garbage", "soon", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := parseRetryAfter(test.header, now); got != test.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", test.header, got, test.want)
+			}
+		})
+	}
+}